@@ -0,0 +1,241 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/AlecAivazis/survey/v2/terminal"
+	"github.com/aphexlog/gsd/internal/output"
+	"github.com/aphexlog/gsd/internal/profiles"
+	"github.com/aphexlog/gsd/internal/regions"
+	"golang.org/x/term"
+)
+
+// errCancelled is returned when the user interrupts a prompt.
+var errCancelled = output.Errorf(output.CodeCancelled, "operation cancelled")
+
+// errNoTerminal is returned instead of prompting when stdin is not a
+// terminal, so a script never ends up writing a half-filled profile.
+var errNoTerminal = output.Errorf(output.CodeNoTerminal, "stdin is not a terminal; pass the values as flags (see --help)")
+
+// robotIcons is the custom survey styling used by the top-level pickers.
+var robotIcons = survey.WithIcons(func(icons *survey.IconSet) {
+	icons.Question.Text = "🤖"
+	icons.Question.Format = "cyan"
+	icons.SelectFocus.Text = "→"
+	icons.SelectFocus.Format = "cyan"
+})
+
+// askOne and ask wrap survey. Prompts are drawn on stderr so stdout only
+// carries the command result, which keeps --output json|yaml parseable,
+// and Ctrl-C becomes errCancelled.
+func askOne(prompt survey.Prompt, response interface{}, opts ...survey.AskOpt) error {
+	if !isTerminal() {
+		return errNoTerminal
+	}
+	opts = append(opts, survey.WithStdio(os.Stdin, os.Stderr, os.Stderr))
+	return promptError(survey.AskOne(prompt, response, opts...))
+}
+
+func ask(questions []*survey.Question, response interface{}, opts ...survey.AskOpt) error {
+	if !isTerminal() {
+		return errNoTerminal
+	}
+	opts = append(opts, survey.WithStdio(os.Stdin, os.Stderr, os.Stderr))
+	return promptError(survey.Ask(questions, response, opts...))
+}
+
+// askFlag prompts for a value that could also have been given as flag. Without
+// a terminal it fails with an error naming the flag.
+func askFlag(flag string, prompt survey.Prompt, response interface{}, opts ...survey.AskOpt) error {
+	if err := requireTerminal(flag); err != nil {
+		return err
+	}
+	return askOne(prompt, response, opts...)
+}
+
+// requireTerminal returns a no_terminal error naming the flags that would
+// have made the prompts that follow unnecessary.
+func requireTerminal(flags string) error {
+	if isTerminal() {
+		return nil
+	}
+	return output.Errorf(output.CodeNoTerminal, "stdin is not a terminal; pass %s", flags)
+}
+
+// isTerminal reports whether prompts can be shown.
+func isTerminal() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+func promptError(err error) error {
+	if errors.Is(err, terminal.InterruptErr) {
+		return errCancelled
+	}
+	if err != nil {
+		return output.Errorf(output.CodeError, "prompt failed: %w", err)
+	}
+	return nil
+}
+
+// defaultOption returns value if it is one of options, or nil so survey
+// does not reject a default that is no longer in the list.
+func defaultOption(options []string, value string) interface{} {
+	for _, option := range options {
+		if option == value {
+			return value
+		}
+	}
+	return nil
+}
+
+// regionSelect is a picker over the region catalog. Typing filters by code
+// or name, e.g. "frank" finds eu-central-1.
+func regionSelect(message, current string) *survey.Select {
+	catalog := regions.All()
+	codes := make([]string, len(catalog))
+	for i, r := range catalog {
+		codes[i] = r.Code
+	}
+	return &survey.Select{
+		Message:  message,
+		Options:  codes,
+		Default:  defaultOption(codes, current),
+		PageSize: 12,
+		Description: func(value string, index int) string {
+			return describeRegion(catalog[index])
+		},
+		Filter: func(filter, value string, index int) bool {
+			return catalog[index].Matches(filter)
+		},
+	}
+}
+
+// describeRegion renders a region's name, partition and opt-in status.
+func describeRegion(r regions.Region) string {
+	var parts []string
+	if r.Name != "" {
+		parts = append(parts, r.Name)
+	}
+	if r.Partition != regions.PartitionAWS {
+		parts = append(parts, r.Partition)
+	}
+	if r.OptIn {
+		parts = append(parts, "opt-in")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// validateAccountID is a survey validator for 12-digit AWS account IDs.
+func validateAccountID(val interface{}) error {
+	str, _ := val.(string)
+	if len(str) != 12 {
+		return fmt.Errorf("account ID must be 12 digits")
+	}
+	for _, r := range str {
+		if r < '0' || r > '9' {
+			return fmt.Errorf("account ID must be 12 digits")
+		}
+	}
+	return nil
+}
+
+const newSSOSessionOption = "➕ Create a new SSO session"
+
+// askSSOSession lets the user pick an existing [sso-session] block or
+// describe a new one. It reports whether the session still has to be written.
+func askSSOSession(store *profiles.Store, region, current string) (*profiles.SSOSession, bool, error) {
+	options := append(store.SSOSessionNames(), newSSOSessionOption)
+
+	choice := newSSOSessionOption
+	if len(options) > 1 {
+		prompt := &survey.Select{
+			Message: "SSO session:",
+			Options: options,
+			Default: defaultOption(options, current),
+			Help:    "Profiles that share a session share one 'aws sso login'",
+		}
+		if err := askOne(prompt, &choice); err != nil {
+			return nil, false, err
+		}
+	}
+
+	if choice != newSSOSessionOption {
+		sess, _ := store.GetSSOSession(choice)
+		return sess, false, nil
+	}
+
+	sess := &profiles.SSOSession{
+		Region:             region,
+		RegistrationScopes: profiles.DefaultRegistrationScopes,
+	}
+	if err := askSSOSessionSettings(store, sess, true); err != nil {
+		return nil, false, err
+	}
+	return sess, true, nil
+}
+
+// askSSOSessionSettings prompts for the fields of an sso-session block,
+// using the current values of sess as defaults. The name is only asked for
+// new sessions, since renaming would orphan the profiles that use it.
+func askSSOSessionSettings(store *profiles.Store, sess *profiles.SSOSession, askName bool) error {
+	var questions []*survey.Question
+	if askName {
+		questions = append(questions, &survey.Question{
+			Name: "Name",
+			Prompt: &survey.Input{
+				Message: "SSO session name:",
+				Help:    "A short name for this IAM Identity Center instance, e.g. your org",
+			},
+			Validate: func(val interface{}) error {
+				str, _ := val.(string)
+				if str == "" {
+					return fmt.Errorf("session name is required")
+				}
+				if _, exists := store.GetSSOSession(str); exists {
+					return fmt.Errorf("SSO session '%s' already exists", str)
+				}
+				return nil
+			},
+		})
+	}
+	questions = append(questions,
+		&survey.Question{
+			Name: "StartURL",
+			Prompt: &survey.Input{
+				Message: "SSO start URL:",
+				Default: sess.StartURL,
+				Help:    "Enter your AWS SSO start URL",
+			},
+			Validate: survey.Required,
+		},
+		&survey.Question{
+			Name:   "Region",
+			Prompt: regionSelect("SSO region:", sess.Region),
+		},
+		&survey.Question{
+			Name: "RegistrationScopes",
+			Prompt: &survey.Input{
+				Message: "SSO registration scopes:",
+				Default: sess.RegistrationScopes,
+				Help:    "Comma-separated OAuth scopes; sso:account:access is enough for most setups",
+			},
+		},
+	)
+	return ask(questions, sess)
+}
+
+// describeProfiles returns a survey Description func that shows each
+// profile's type, account, region and credential status next to its name.
+func describeProfiles(store *profiles.Store) func(value string, index int) string {
+	infos := profileInfos(store)
+	return func(value string, index int) string {
+		if info, ok := infos[value]; ok {
+			return info.describe()
+		}
+		return ""
+	}
+}
@@ -0,0 +1,330 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/aphexlog/gsd/internal/output"
+	"github.com/aphexlog/gsd/internal/profiles"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	exportProfiles       []string
+	exportIncludeSecrets bool
+
+	importDryRun     bool
+	importOnConflict string
+)
+
+var configExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Write profiles to a portable bundle",
+	Long: `Write profiles, and the SSO sessions and services sections they use, as a
+YAML bundle on stdout. Credentials are left out unless --include-secrets is
+given.
+
+  gsd config export > bundle.yaml
+  gsd config export --profiles 'prod-*' > prod.yaml`,
+	Args: cobra.NoArgs,
+	RunE: runResult(func(cmd *cobra.Command, args []string) (any, error) {
+		for _, pattern := range exportProfiles {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, output.Errorf(output.CodeInvalidArgument, "invalid --profiles pattern '%s': %w", pattern, err)
+			}
+		}
+
+		store, err := loadStore()
+		if err != nil {
+			return nil, err
+		}
+
+		bundle := store.Export(func(name string) bool {
+			if len(exportProfiles) == 0 {
+				return true
+			}
+			for _, pattern := range exportProfiles {
+				if ok, _ := path.Match(pattern, name); ok {
+					return true
+				}
+			}
+			return false
+		}, exportIncludeSecrets)
+		if len(bundle.Profiles) == 0 {
+			return nil, output.Errorf(output.CodeNotFound, "no profiles to export")
+		}
+		return bundle, nil
+	}),
+}
+
+// Conflict resolutions offered by config import.
+const (
+	conflictSkip      = "skip"
+	conflictOverwrite = "overwrite"
+	conflictRename    = "rename"
+)
+
+var configImportCmd = &cobra.Command{
+	Use:   "import <bundle.yaml>",
+	Short: "Add profiles from a bundle written by 'gsd config export'",
+	Long: `Add the profiles, SSO sessions and services sections in a bundle. Sections
+that already exist with different settings are conflicts: gsd shows the
+differences and asks whether to skip, overwrite or rename each one, unless
+--on-conflict decides for all of them. Use - to read the bundle from stdin.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runResult(func(cmd *cobra.Command, args []string) (any, error) {
+		switch importOnConflict {
+		case "", conflictSkip, conflictOverwrite, conflictRename:
+		default:
+			return nil, output.Errorf(output.CodeInvalidArgument, "unknown --on-conflict '%s' (use skip, overwrite or rename)", importOnConflict)
+		}
+
+		bundle, err := readBundle(cmd.InOrStdin(), args[0])
+		if err != nil {
+			return nil, err
+		}
+		store, err := loadStore()
+		if err != nil {
+			return nil, err
+		}
+
+		// Conflicts are settled before taking the lock, so it isn't held
+		// while waiting on the user; the plan is then rebuilt under the
+		// lock with those answers, against the files as they are now.
+		choices := make(map[profiles.Target]importChoice)
+		if importOnConflict == "" && !importDryRun {
+			if _, err := planBundle(store, bundle.Copy(), choices, true); err != nil {
+				return nil, err
+			}
+		}
+
+		var result *importResult
+		err = updateStore(func(store *profiles.Store) error {
+			var err error
+			result, err = planBundle(store, bundle, choices, false)
+			if err != nil || importDryRun {
+				return err
+			}
+			for _, item := range result.Items {
+				if item.Action == importAdd || item.Action == conflictOverwrite {
+					store.Import(item.Kind, *item.section)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to import bundle: %w", err)
+		}
+		return result, nil
+	}),
+}
+
+// readBundle parses a bundle file, or stdin for "-".
+func readBundle(stdin io.Reader, file string) (*profiles.Bundle, error) {
+	var data []byte
+	var err error
+	if file == "-" {
+		data, err = io.ReadAll(stdin)
+	} else {
+		data, err = os.ReadFile(file)
+	}
+	if err != nil {
+		return nil, output.Errorf(output.CodeNotFound, "unable to read bundle: %w", err)
+	}
+
+	var bundle profiles.Bundle
+	if err := yaml.Unmarshal(data, &bundle); err != nil {
+		return nil, output.Errorf(output.CodeInvalidArgument, "unable to parse bundle: %w", err)
+	}
+	if err := bundle.Validate(); err != nil {
+		return nil, output.Errorf(output.CodeInvalidArgument, "%w", err)
+	}
+	return &bundle, nil
+}
+
+// Import actions besides the conflict resolutions.
+const (
+	importAdd       = "add"
+	importUnchanged = "unchanged"
+	importAsk       = "ask"
+)
+
+// importChoice is the answer to a conflict: the action and, for a rename,
+// the new name.
+type importChoice struct {
+	action string
+	name   string
+}
+
+// planBundle plans the import of every section of the bundle. Sessions and
+// services go first so renaming one is reflected in the profiles that use
+// it before they are compared.
+func planBundle(store *profiles.Store, bundle *profiles.Bundle, choices map[profiles.Target]importChoice, ask bool) (*importResult, error) {
+	result := &importResult{DryRun: importDryRun}
+	for _, kind := range []profiles.SectionKind{profiles.SSOSessionSection, profiles.ServicesSection, profiles.ProfileSection} {
+		sections := bundle.Sections(kind)
+		for i := range *sections {
+			item, err := planImport(store, bundle, kind, &(*sections)[i], choices, ask)
+			if err != nil {
+				return nil, err
+			}
+			result.Items = append(result.Items, item)
+		}
+	}
+	return result, nil
+}
+
+// planImport compares one bundle section with the store and decides what to
+// do with it. A conflict is resolved by --on-conflict, an earlier answer in
+// choices, or, if ask is set, by asking the user and recording the answer.
+func planImport(store *profiles.Store, bundle *profiles.Bundle, kind profiles.SectionKind, section *profiles.BundleSection, choices map[profiles.Target]importChoice, ask bool) (importItem, error) {
+	status, diff := store.CompareImport(kind, *section)
+	item := importItem{Kind: kind, Name: section.Name, Status: status, Diff: diff, section: section}
+
+	switch status {
+	case profiles.ImportNew:
+		item.Action = importAdd
+		return item, nil
+	case profiles.ImportUnchanged:
+		item.Action = importUnchanged
+		return item, nil
+	}
+
+	target := profiles.Target{Kind: kind, Name: section.Name}
+	choice, chosen := choices[target]
+	if importOnConflict != "" {
+		choice = importChoice{action: importOnConflict}
+	} else if !chosen && importDryRun {
+		item.Action = importAsk
+		return item, nil
+	} else if !chosen && !ask {
+		return item, output.Errorf(output.CodeConflict, "%s '%s' changed while importing; run the import again", kind, section.Name)
+	} else if !chosen {
+		var err error
+		if choice, err = askImportChoice(store, bundle, kind, section.Name, diff); err != nil {
+			return item, err
+		}
+		choices[target] = choice
+	}
+
+	item.Action = choice.action
+	if item.Action == conflictRename {
+		name := choice.name
+		if name == "" {
+			name = importName(store, bundle, kind, section.Name)
+		}
+		if store.Exists(profiles.Target{Kind: kind, Name: name}) {
+			return item, output.Errorf(output.CodeConflict, "%s '%s' already exists", kind, name)
+		}
+		bundle.Rename(kind, section.Name, name)
+		item.RenamedTo = name
+		item.Action = importAdd
+		// The renamed section is new, so its diff is everything it adds.
+		_, item.Diff = store.CompareImport(kind, *section)
+	}
+	return item, nil
+}
+
+// askImportChoice shows a conflict and asks how to resolve it.
+func askImportChoice(store *profiles.Store, bundle *profiles.Bundle, kind profiles.SectionKind, name string, diff []string) (importChoice, error) {
+	fmt.Fprintf(os.Stderr, "⚠️  %s '%s' already exists with different settings:\n", kind, name)
+	for _, line := range diff {
+		fmt.Fprintf(os.Stderr, "      %s\n", line)
+	}
+	var choice importChoice
+	err := askFlag("--on-conflict", &survey.Select{
+		Message: fmt.Sprintf("What should happen to %s '%s'?", kind, name),
+		Options: []string{conflictSkip, conflictOverwrite, conflictRename},
+	}, &choice.action)
+	if err != nil || choice.action != conflictRename {
+		return choice, err
+	}
+
+	choice.name = importName(store, bundle, kind, name)
+	err = askOne(&survey.Input{Message: "New name:", Default: choice.name}, &choice.name, survey.WithValidator(func(val interface{}) error {
+		str, _ := val.(string)
+		if err := validateProfileName(str); err != nil {
+			return err
+		}
+		if store.Exists(profiles.Target{Kind: kind, Name: str}) {
+			return fmt.Errorf("%s '%s' already exists", kind, str)
+		}
+		return nil
+	}))
+	return choice, err
+}
+
+// importName picks a free name for a renamed section.
+func importName(store *profiles.Store, bundle *profiles.Bundle, kind profiles.SectionKind, name string) string {
+	taken := func(candidate string) bool {
+		if store.Exists(profiles.Target{Kind: kind, Name: candidate}) {
+			return true
+		}
+		for _, section := range *bundle.Sections(kind) {
+			if section.Name == candidate {
+				return true
+			}
+		}
+		return false
+	}
+
+	candidate := name + "-imported"
+	for i := 2; taken(candidate); i++ {
+		candidate = fmt.Sprintf("%s-imported-%d", name, i)
+	}
+	return candidate
+}
+
+// importItem is the plan for one section of the bundle.
+type importItem struct {
+	Kind      profiles.SectionKind  `json:"kind" yaml:"kind"`
+	Name      string                `json:"name" yaml:"name"`
+	Status    profiles.ImportStatus `json:"status" yaml:"status"`
+	Action    string                `json:"action" yaml:"action"`
+	RenamedTo string                `json:"renamed_to,omitempty" yaml:"renamed_to,omitempty"`
+	Diff      []string              `json:"diff,omitempty" yaml:"diff,omitempty"`
+
+	section *profiles.BundleSection
+}
+
+// importResult is the result of `gsd config import`.
+type importResult struct {
+	DryRun bool         `json:"dry_run" yaml:"dry_run"`
+	Items  []importItem `json:"items" yaml:"items"`
+}
+
+func (r *importResult) Text(w io.Writer) {
+	if r.DryRun {
+		fmt.Fprintln(w, "📦 Import plan (dry run, nothing was changed):")
+	} else {
+		fmt.Fprintln(w, "✨ Import complete:")
+	}
+
+	for _, item := range r.Items {
+		name := item.Name
+		if item.RenamedTo != "" {
+			name += " → " + item.RenamedTo
+		}
+		fmt.Fprintf(w, "   %-10s %s '%s' (%s)\n", item.Action, item.Kind, name, item.Status)
+		if r.DryRun && item.Action != importUnchanged && item.Action != conflictSkip {
+			for _, line := range item.Diff {
+				fmt.Fprintf(w, "      %s\n", line)
+			}
+		}
+	}
+}
+
+func init() {
+	configExportCmd.Flags().StringArrayVar(&exportProfiles, "profiles", nil, "Only export profiles matching this glob (repeatable)")
+	configExportCmd.Flags().BoolVar(&exportIncludeSecrets, "include-secrets", false, "Include credentials such as access keys")
+
+	configImportCmd.Flags().BoolVar(&importDryRun, "dry-run", false, "Print the planned changes without writing anything")
+	configImportCmd.Flags().StringVar(&importOnConflict, "on-conflict", "", "Resolve every conflict the same way: skip, overwrite or rename")
+
+	configCmd.AddCommand(configExportCmd)
+	configCmd.AddCommand(configImportCmd)
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/aphexlog/gsd/internal/profiles"
+)
+
+func TestPlanBundle(t *testing.T) {
+	dev := profiles.Target{Kind: profiles.ProfileSection, Name: "dev"}
+	cases := []struct {
+		name       string
+		onConflict string
+		dryRun     bool
+		choices    map[profiles.Target]importChoice
+		action     string // for dev, which conflicts
+		renamedTo  string
+		err        bool
+	}{
+		{name: "skip", onConflict: conflictSkip, action: conflictSkip},
+		{name: "overwrite", onConflict: conflictOverwrite, action: conflictOverwrite},
+		{name: "rename", onConflict: conflictRename, action: importAdd, renamedTo: "dev-imported-2"},
+		{name: "earlier answer", choices: map[profiles.Target]importChoice{dev: {action: conflictRename, name: "dev2"}}, action: importAdd, renamedTo: "dev2"},
+		{name: "rename onto a profile", choices: map[profiles.Target]importChoice{dev: {action: conflictRename, name: "prod"}}, err: true},
+		{name: "dry run without an answer", dryRun: true, action: importAsk},
+		{name: "no answer", err: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			importOnConflict, importDryRun = tc.onConflict, tc.dryRun
+			t.Cleanup(func() { importOnConflict, importDryRun = "", false })
+
+			store := loadTestStore(t, "[profile dev]\nregion = us-east-1\n\n[profile dev-imported]\nregion = us-east-1\n\n[profile prod]\nregion = eu-west-1\n")
+			bundle := &profiles.Bundle{Version: profiles.BundleVersion, Profiles: []profiles.BundleSection{
+				{Name: "dev", Settings: map[string]string{"region": "eu-central-1"}},
+				{Name: "prod", Settings: map[string]string{"region": "eu-west-1"}},
+				{Name: "new", Settings: map[string]string{"region": "us-west-2"}},
+			}}
+			choices := tc.choices
+			if choices == nil {
+				choices = make(map[profiles.Target]importChoice)
+			}
+
+			result, err := planBundle(store, bundle, choices, false)
+			if (err != nil) != tc.err {
+				t.Fatalf("planBundle() error = %v, want error %v", err, tc.err)
+			}
+			if err != nil {
+				return
+			}
+			want := map[string]string{"dev": tc.action, "prod": importUnchanged, "new": importAdd}
+			for _, item := range result.Items {
+				if item.Action != want[item.Name] {
+					t.Errorf("%s: action %s, want %s", item.Name, item.Action, want[item.Name])
+				}
+				if item.Name == "dev" && item.RenamedTo != tc.renamedTo {
+					t.Errorf("dev renamed to %q, want %q", item.RenamedTo, tc.renamedTo)
+				}
+			}
+			if tc.renamedTo != "" && (*bundle.Sections(profiles.ProfileSection))[0].Name != tc.renamedTo {
+				t.Error("the bundle section was not renamed")
+			}
+		})
+	}
+}
@@ -2,14 +2,15 @@ package cmd
 
 import (
 	"fmt"
-	"log"
-	"os"
-	"path/filepath"
+	"io"
+	"path"
 	"strings"
+	"text/tabwriter"
 
 	"github.com/AlecAivazis/survey/v2"
+	"github.com/aphexlog/gsd/internal/output"
+	"github.com/aphexlog/gsd/internal/profiles"
 	"github.com/spf13/cobra"
-	"gopkg.in/ini.v1"
 )
 
 var configCmd = &cobra.Command{
@@ -21,211 +22,337 @@ type ProfileConfig struct {
 	Name            string
 	Region          string
 	AuthType        string
-	SSOStartURL     string
-	SSORegion       string
+	SSOSession      string
 	SSOAccountID    string
 	SSORoleName     string
 	AccessKeyID     string
 	SecretAccessKey string
 }
 
-var regions = []string{
-	"us-east-1", "us-east-2", "us-west-1", "us-west-2",
-	"eu-west-1", "eu-west-2", "eu-central-1",
-	"ap-southeast-1", "ap-southeast-2", "ap-northeast-1",
+const (
+	roleFromProfile          = "Another profile (source_profile)"
+	roleFromCredentialSource = "The environment, EC2 or ECS (credential_source)"
+)
+
+var lsFilters []string
+
+// profileListing is one row of `gsd config ls`.
+type profileListing struct {
+	Name       string `json:"name" yaml:"name"`
+	Type       string `json:"type" yaml:"type"`
+	Region     string `json:"region,omitempty" yaml:"region,omitempty"`
+	AccountID  string `json:"account_id,omitempty" yaml:"account_id,omitempty"`
+	Role       string `json:"role,omitempty" yaml:"role,omitempty"`
+	SSOSession string `json:"sso_session,omitempty" yaml:"sso_session,omitempty"`
+	Source     string `json:"source" yaml:"source"`
+	Active     bool   `json:"active" yaml:"active"`
 }
 
 var configLsCmd = &cobra.Command{
 	Use:   "ls",
 	Short: "List all AWS profiles",
-	Run: func(cmd *cobra.Command, args []string) {
-		configPath := filepath.Join(os.Getenv("HOME"), ".aws", "config")
-		credPath := filepath.Join(os.Getenv("HOME"), ".aws", "credentials")
-
-		profiles := make(map[string]bool)
-
-		if cfg, err := ini.Load(configPath); err == nil {
-			for _, section := range cfg.Sections() {
-				name := section.Name()
-				if name == ini.DefaultSection {
-					profiles["default"] = true
-				} else if strings.HasPrefix(name, "profile ") {
-					profiles[strings.TrimPrefix(name, "profile ")] = true
-				}
+	Long: `List all AWS profiles, sorted by name, with the active profile marked.
+
+Filters take the form field=glob, where field is name, type or region.
+A bare glob matches the name. Repeat --filter to combine them.
+
+  gsd config ls --filter type=sso --filter 'name=prod-*'
+  gsd config ls --output json`,
+	RunE: runResult(func(cmd *cobra.Command, args []string) (any, error) {
+		store, err := loadStore()
+		if err != nil {
+			return nil, err
+		}
+		active := activeProfile()
+
+		rows := profileList{}
+		for _, p := range store.Profiles() {
+			row := profileListing{
+				Name:       p.Name,
+				Type:       string(p.Type),
+				Region:     p.Region,
+				AccountID:  p.AccountID(),
+				Role:       p.RoleName(),
+				SSOSession: p.SSOSession,
+				Source:     p.Source.String(),
+				Active:     p.Name == active,
+			}
+			ok, err := matchesFilters(row, lsFilters)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				rows = append(rows, row)
 			}
 		}
+		return rows, nil
+	}),
+}
 
-		if creds, err := ini.Load(credPath); err == nil {
-			for _, section := range creds.Sections() {
-				name := section.Name()
-				if name == ini.DefaultSection {
-					profiles["default"] = true
-				} else {
-					profiles[name] = true
-				}
-			}
+// profileList is the result of `gsd config ls`.
+type profileList []profileListing
+
+func (rows profileList) Text(out io.Writer) {
+	if len(rows) == 0 {
+		fmt.Fprintln(out, "🤖 No matching AWS profiles")
+		return
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, " \tNAME\tTYPE\tREGION\tACCOUNT\tROLE\tSSO SESSION\tSOURCE")
+	for _, row := range rows {
+		marker := " "
+		if row.Active {
+			marker = "*"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			marker, row.Name, row.Type, row.Region, row.AccountID, row.Role, row.SSOSession, row.Source)
+	}
+	w.Flush()
+}
+
+// matchesFilters applies --filter expressions to a listing row.
+func matchesFilters(row profileListing, filters []string) (bool, error) {
+	for _, filter := range filters {
+		field, pattern, found := strings.Cut(filter, "=")
+		if !found {
+			field, pattern = "name", filter
+		}
+
+		var value string
+		switch field {
+		case "name":
+			value = row.Name
+		case "type":
+			value = row.Type
+		case "region":
+			value = row.Region
+		default:
+			return false, output.Errorf(output.CodeInvalidArgument, "unknown filter field '%s' (use name, type or region)", field)
 		}
 
-		fmt.Println("✨ Available AWS profiles:")
-		for name := range profiles {
-			fmt.Printf("   %s\n", name)
+		ok, err := path.Match(pattern, value)
+		if err != nil {
+			return false, output.Errorf(output.CodeInvalidArgument, "invalid filter pattern '%s': %w", pattern, err)
 		}
-	},
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
 }
 
+var addFlags profileFlags
+
 var configAddCmd = &cobra.Command{
 	Use:   "add",
 	Short: "Add a new AWS profile",
-	Run: func(cmd *cobra.Command, args []string) {
-		profile := &ProfileConfig{}
+	Long: `Add a new AWS profile. Values given as flags are not prompted for, and
+without a terminal every required value must be given as a flag.
+
+  gsd config add --name dev --region eu-west-1 \
+    --sso-session corp --sso-account-id 123456789012 --sso-role-name Admin
+  gsd config add --name ci --access-key-id AKIA... --secret-access-key-stdin <<< "$SECRET"`,
+	Args: cobra.NoArgs,
+	RunE: runResult(func(cmd *cobra.Command, args []string) (any, error) {
+		if err := addFlags.validate(); err != nil {
+			return nil, err
+		}
+		authType, err := addFlags.authType()
+		if err != nil {
+			return nil, err
+		}
+
+		store, err := loadStore()
+		if err != nil {
+			return nil, err
+		}
+
+		profile := &ProfileConfig{
+			Name:         addFlags.Name,
+			Region:       addFlags.Region,
+			AuthType:     authType,
+			SSOAccountID: addFlags.SSOAccountID,
+			SSORoleName:  addFlags.SSORoleName,
+			AccessKeyID:  addFlags.AccessKeyID,
+		}
 
 		// Profile name
-		namePrompt := &survey.Input{
-			Message: "Profile name:",
-			Help:    "Enter a unique name for this profile",
+		if profile.Name == "" {
+			namePrompt := &survey.Input{
+				Message: "Profile name:",
+				Help:    "Enter a unique name for this profile",
+			}
+			validName := func(ans interface{}) error {
+				return checkNewProfileName(store, ans.(string))
+			}
+			if err := askFlag("--name", namePrompt, &profile.Name, survey.WithValidator(validName)); err != nil {
+				return nil, err
+			}
+		} else if err := checkNewProfileName(store, profile.Name); err != nil {
+			return nil, err
 		}
-		survey.AskOne(namePrompt, &profile.Name, survey.WithValidator(survey.Required))
 
 		// Region selection
-		regionPrompt := &survey.Select{
-			Message: "Select AWS region:",
-			Options: regions,
-			Default: "us-east-1",
+		if profile.Region == "" {
+			regionPrompt := regionSelect("Select AWS region:", "us-east-1")
+			if err := askFlag("--region", regionPrompt, &profile.Region); err != nil {
+				return nil, err
+			}
 		}
-		survey.AskOne(regionPrompt, &profile.Region)
 
 		// Authentication type
-		authTypePrompt := &survey.Select{
-			Message: "Choose authentication method:",
-			Options: []string{"AWS SSO", "Access Keys"},
-			Default: "AWS SSO",
+		if profile.AuthType == "" {
+			authTypePrompt := &survey.Select{
+				Message: "Choose authentication method:",
+				Options: []string{authSSO, authAccessKeys, authAssumeRole},
+				Default: authSSO,
+			}
+			if err := askFlag("--sso-session, --role-arn or --access-key-id", authTypePrompt, &profile.AuthType); err != nil {
+				return nil, err
+			}
 		}
-		survey.AskOne(authTypePrompt, &profile.AuthType)
 
-		if profile.AuthType == "AWS SSO" {
-			ssoQuestions := []*survey.Question{
-				{
-					Name: "SSOStartURL",
-					Prompt: &survey.Input{
-						Message: "SSO start URL:",
-						Help:    "Enter your AWS SSO start URL",
-					},
-					Validate: survey.Required,
-				},
-				{
-					Name: "SSORegion",
-					Prompt: &survey.Select{
-						Message: "SSO region:",
-						Options: regions,
-						Default: profile.Region,
-					},
-				},
-				{
-					Name: "SSOAccountID",
-					Prompt: &survey.Input{
-						Message: "AWS Account ID:",
-						Help:    "Enter your 12-digit AWS account ID",
-					},
-					Validate: func(val interface{}) error {
-						str, _ := val.(string)
-						if len(str) != 12 {
-							return fmt.Errorf("account ID must be 12 digits")
-						}
-						return nil
-					},
-				},
-				{
-					Name: "SSORoleName",
-					Prompt: &survey.Input{
-						Message: "SSO Role name:",
-						Help:    "Enter the IAM role name for SSO",
-					},
-					Validate: survey.Required,
-				},
+		var newSession *profiles.SSOSession
+		var roleSettings map[string]string
+		switch profile.AuthType {
+		case authSSO:
+			session, isNew, err := addFlags.ssoSession(store, profile.Region)
+			if err != nil {
+				return nil, err
 			}
-			survey.Ask(ssoQuestions, profile)
-		} else {
-			accessKeyQuestions := []*survey.Question{
-				{
-					Name: "AccessKeyID",
-					Prompt: &survey.Input{
-						Message: "AWS Access Key ID:",
-					},
-					Validate: survey.Required,
-				},
-				{
-					Name: "SecretAccessKey",
-					Prompt: &survey.Password{
-						Message: "AWS Secret Access Key:",
-					},
-					Validate: survey.Required,
-				},
+			if session == nil {
+				if err := requireTerminal("--sso-session"); err != nil {
+					return nil, err
+				}
+				if session, isNew, err = askSSOSession(store, profile.Region, ""); err != nil {
+					return nil, err
+				}
+			}
+			profile.SSOSession = session.Name
+			if isNew {
+				newSession = session
 			}
-			survey.Ask(accessKeyQuestions, profile)
-		}
 
-		// Save the profile
-		configPath := filepath.Join(os.Getenv("HOME"), ".aws", "config")
-		credPath := filepath.Join(os.Getenv("HOME"), ".aws", "credentials")
+			if profile.SSOAccountID == "" {
+				accountPrompt := &survey.Input{
+					Message: "AWS Account ID:",
+					Help:    "Enter your 12-digit AWS account ID",
+				}
+				if err := askFlag("--sso-account-id", accountPrompt, &profile.SSOAccountID, survey.WithValidator(validateAccountID)); err != nil {
+					return nil, err
+				}
+			}
+			if profile.SSORoleName == "" {
+				rolePrompt := &survey.Input{
+					Message: "SSO Role name:",
+					Help:    "Enter the IAM role name for SSO",
+				}
+				if err := askFlag("--sso-role-name", rolePrompt, &profile.SSORoleName, survey.WithValidator(survey.Required)); err != nil {
+					return nil, err
+				}
+			}
 
-		cfg, _ := ini.LooseLoad(configPath)
-		creds, _ := ini.LooseLoad(credPath)
+		case authAssumeRole:
+			p := &profiles.Profile{Name: profile.Name, Region: profile.Region}
+			if addFlags.hasRole() {
+				roleSettings, err = addFlags.roleSettings(store, p)
+				if err != nil {
+					return nil, err
+				}
+				break
+			}
 
-		sectionName := "profile " + profile.Name
-		section := cfg.Section(sectionName)
-		section.Key("region").SetValue(profile.Region)
+			var base string
+			err := askFlag("--role-arn", &survey.Select{
+				Message: "Where do the base credentials come from?",
+				Options: []string{roleFromProfile, roleFromCredentialSource},
+			}, &base)
+			if err != nil {
+				return nil, err
+			}
 
-		if profile.AuthType == "AWS SSO" {
-			section.Key("sso_start_url").SetValue(profile.SSOStartURL)
-			section.Key("sso_region").SetValue(profile.SSORegion)
-			section.Key("sso_account_id").SetValue(profile.SSOAccountID)
-			section.Key("sso_role_name").SetValue(profile.SSORoleName)
-		} else {
-			credSection := creds.Section(profile.Name)
-			credSection.Key("aws_access_key_id").SetValue(profile.AccessKeyID)
-			credSection.Key("aws_secret_access_key").SetValue(profile.SecretAccessKey)
-		}
+			roleType := profiles.TypeAssumeRole
+			if base == roleFromCredentialSource {
+				roleType = profiles.TypeCredentialSource
+			}
+			roleSettings, err = askRoleSettings(store, roleType, p)
+			if err != nil {
+				return nil, err
+			}
 
-		if err := cfg.SaveTo(configPath); err != nil {
-			log.Fatalf("Failed to write config file: %v", err)
+		default:
+			if profile.AccessKeyID == "" {
+				keyPrompt := &survey.Input{
+					Message: "AWS Access Key ID:",
+				}
+				if err := askFlag("--access-key-id", keyPrompt, &profile.AccessKeyID, survey.WithValidator(survey.Required)); err != nil {
+					return nil, err
+				}
+			}
+			profile.SecretAccessKey, err = addFlags.secretAccessKey(cmd.InOrStdin(), "AWS Secret Access Key:")
+			if err != nil {
+				return nil, err
+			}
 		}
-		if err := creds.SaveTo(credPath); err != nil {
-			log.Fatalf("Failed to write credentials file: %v", err)
+
+		// Save the profile
+		err = updateStore(func(store *profiles.Store) error {
+			// Another gsd may have taken the name while we were asking.
+			if store.Has(profile.Name) {
+				return output.Errorf(output.CodeConflict, "profile '%s' already exists", profile.Name)
+			}
+			store.SetConfig(profile.Name, "region", profile.Region)
+
+			switch profile.AuthType {
+			case authSSO:
+				if newSession != nil {
+					store.PutSSOSession(newSession)
+				}
+				store.SetConfig(profile.Name, "sso_session", profile.SSOSession)
+				store.SetConfig(profile.Name, "sso_account_id", profile.SSOAccountID)
+				store.SetConfig(profile.Name, "sso_role_name", profile.SSORoleName)
+			case authAssumeRole:
+				applySettings(store, profile.Name, roleSettings)
+				if _, err := store.RoleChain(profile.Name); err != nil {
+					return err
+				}
+			default:
+				store.SetCredential(profile.Name, "aws_access_key_id", profile.AccessKeyID)
+				store.SetCredential(profile.Name, "aws_secret_access_key", profile.SecretAccessKey)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to save profile: %w", err)
 		}
 
-		fmt.Printf("✨ Profile '%s' created successfully!\n", profile.Name)
-	},
+		return &profileResult{Action: "created", Profile: profile.Name}, nil
+	}),
 }
 
 var configRemoveCmd = &cobra.Command{
 	Use:   "remove",
 	Short: "Remove an AWS profile",
-	Run: func(cmd *cobra.Command, args []string) {
-		configPath := filepath.Join(os.Getenv("HOME"), ".aws", "config")
-		credPath := filepath.Join(os.Getenv("HOME"), ".aws", "credentials")
-
-		// Get existing profiles
-		profiles := make([]string, 0)
-		if cfg, err := ini.Load(configPath); err == nil {
-			for _, section := range cfg.Sections() {
-				name := section.Name()
-				if strings.HasPrefix(name, "profile ") {
-					profiles = append(profiles, strings.TrimPrefix(name, "profile "))
-				}
-			}
+	RunE: runResult(func(cmd *cobra.Command, args []string) (any, error) {
+		store, err := loadStore()
+		if err != nil {
+			return nil, err
 		}
+		names := store.Names()
 
-		if len(profiles) == 0 {
-			fmt.Println("❌ No profiles found to remove")
-			return
+		if len(names) == 0 {
+			return nil, output.Errorf(output.CodeNotFound, "no profiles found to remove")
 		}
 
 		var selectedProfile string
 		prompt := &survey.Select{
-			Message: "Choose a profile to remove:",
-			Options: profiles,
+			Message:     "Choose a profile to remove:",
+			Options:     names,
+			Description: describeProfiles(store),
+		}
+		if err := askOne(prompt, &selectedProfile); err != nil {
+			return nil, err
 		}
-		survey.AskOne(prompt, &selectedProfile)
 
 		var confirm bool
 		confirmPrompt := &survey.Confirm{
@@ -233,96 +360,130 @@ var configRemoveCmd = &cobra.Command{
 			Default: false,
 		}
 
-		survey.AskOne(confirmPrompt, &confirm)
+		if err := askOne(confirmPrompt, &confirm); err != nil {
+			return nil, err
+		}
 		if !confirm {
-			fmt.Println("Operation cancelled")
-			return
+			return cancelled()
 		}
 
-		cfg, _ := ini.LooseLoad(configPath)
-		creds, _ := ini.LooseLoad(credPath)
+		err = updateStore(func(store *profiles.Store) error {
+			store.Remove(selectedProfile)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to save changes: %w", err)
+		}
+		forgetIdentities(selectedProfile)
+
+		return &profileResult{Action: "removed", Profile: selectedProfile}, nil
+	}),
+}
 
-		sectionName := "profile " + selectedProfile
-		if cfg.HasSection(sectionName) {
-			cfg.DeleteSection(sectionName)
+var editFlags profileFlags
+
+var configEditCmd = &cobra.Command{
+	Use:   "edit [name]",
+	Short: "Edit an existing AWS profile",
+	Long: `Edit an existing AWS profile. With setting flags the changes are applied
+directly; otherwise gsd asks what to change.
+
+  gsd config edit dev --region eu-central-1
+  gsd config edit ci --access-key-id AKIA... --secret-access-key-stdin <<< "$SECRET"`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runResult(func(cmd *cobra.Command, args []string) (any, error) {
+		if err := editFlags.validate(); err != nil {
+			return nil, err
 		}
-		if creds.HasSection(selectedProfile) {
-			creds.DeleteSection(selectedProfile)
+		if _, err := editFlags.authType(); err != nil {
+			return nil, err
 		}
 
-		if err := cfg.SaveTo(configPath); err != nil {
-			log.Fatalf("Failed to write config file: %v", err)
-		}
-		if err := creds.SaveTo(credPath); err != nil {
-			log.Fatalf("Failed to write credentials file: %v", err)
+		store, err := loadStore()
+		if err != nil {
+			return nil, err
 		}
+		names := store.Names()
 
-		fmt.Printf("✨ Profile '%s' has been removed\n", selectedProfile)
-	},
-}
+		if len(names) == 0 {
+			return nil, output.Errorf(output.CodeNotFound, "no profiles found to edit")
+		}
 
-var configEditCmd = &cobra.Command{
-	Use:   "edit",
-	Short: "Edit an existing AWS profile",
-	Run: func(cmd *cobra.Command, args []string) {
-		configPath := filepath.Join(os.Getenv("HOME"), ".aws", "config")
-		credPath := filepath.Join(os.Getenv("HOME"), ".aws", "credentials")
-
-		// Get existing profiles
-		profiles := make([]string, 0)
-		if cfg, err := ini.Load(configPath); err == nil {
-			for _, section := range cfg.Sections() {
-				name := section.Name()
-				if strings.HasPrefix(name, "profile ") {
-					profiles = append(profiles, strings.TrimPrefix(name, "profile "))
-				}
+		selectedProfile := editFlags.Name
+		if len(args) == 1 {
+			if selectedProfile != "" && selectedProfile != args[0] {
+				return nil, output.Errorf(output.CodeInvalidArgument, "profile given both as '%s' and --name %s", args[0], selectedProfile)
 			}
+			selectedProfile = args[0]
 		}
-
-		if len(profiles) == 0 {
-			fmt.Println("❌ No profiles found to edit")
-			return
+		if selectedProfile == "" {
+			prompt := &survey.Select{
+				Message:     "Choose a profile to edit:",
+				Options:     names,
+				Description: describeProfiles(store),
+			}
+			if err := askFlag("the profile name", prompt, &selectedProfile); err != nil {
+				return nil, err
+			}
 		}
 
-		var selectedProfile string
-		prompt := &survey.Select{
-			Message: "Choose a profile to edit:",
-			Options: profiles,
+		profile, ok := store.Get(selectedProfile)
+		if !ok {
+			return nil, output.Errorf(output.CodeNotFound, "profile '%s' not found", selectedProfile)
 		}
-		survey.AskOne(prompt, &selectedProfile)
 
-		// Load existing configuration
-		cfg, _ := ini.LooseLoad(configPath)
-		creds, _ := ini.LooseLoad(credPath)
-		sectionName := "profile " + selectedProfile
-		section := cfg.Section(sectionName)
+		if editFlags.hasSettings() {
+			apply, err := editFlags.edit(cmd.InOrStdin(), store, profile)
+			if err != nil {
+				return nil, err
+			}
+			if err := updateStore(apply); err != nil {
+				return nil, fmt.Errorf("failed to save profile: %w", err)
+			}
+			return &profileResult{Action: "updated", Profile: selectedProfile}, nil
+		}
 
 		// Determine what to edit
 		editOptions := []string{"Region"}
-		if section.HasKey("sso_start_url") {
-			editOptions = append(editOptions, "SSO Configuration")
+		if profile.SSOSession != "" {
+			editOptions = append(editOptions, "SSO Account & Role", "SSO Session")
+		} else if profile.SSOStartURL != "" {
+			editOptions = append(editOptions, "SSO Configuration", "Move to an SSO session")
 		}
-		if creds.HasSection(selectedProfile) {
+		if profile.Type.IsRole() {
+			editOptions = append(editOptions, "Role Settings")
+		}
+		if profile.Type == profiles.TypeCredentialProcess {
+			editOptions = append(editOptions, "Credential Process")
+		}
+		if store.HasCredentials(selectedProfile) {
 			editOptions = append(editOptions, "Access Keys")
 		}
 
+		// Changes are collected here and applied under the lock once the
+		// prompts are done
+		var apply func(store *profiles.Store) error
+
 		var editChoice string
 		editPrompt := &survey.Select{
 			Message: "What would you like to edit?",
 			Options: editOptions,
 		}
-		survey.AskOne(editPrompt, &editChoice)
+		if err := askFlag("the settings to change as flags", editPrompt, &editChoice); err != nil {
+			return nil, err
+		}
 
 		switch editChoice {
 		case "Region":
 			var newRegion string
-			regionPrompt := &survey.Select{
-				Message: "Select new AWS region:",
-				Options: regions,
-				Default: section.Key("region").Value(),
+			regionPrompt := regionSelect("Select new AWS region:", profile.Region)
+			if err := askOne(regionPrompt, &newRegion); err != nil {
+				return nil, err
+			}
+			apply = func(store *profiles.Store) error {
+				store.SetConfig(selectedProfile, "region", newRegion)
+				return nil
 			}
-			survey.AskOne(regionPrompt, &newRegion)
-			section.Key("region").SetValue(newRegion)
 
 		case "SSO Configuration":
 			ssoQuestions := []*survey.Question{
@@ -330,36 +491,26 @@ var configEditCmd = &cobra.Command{
 					Name: "ssoStartURL",
 					Prompt: &survey.Input{
 						Message: "New SSO start URL:",
-						Default: section.Key("sso_start_url").Value(),
+						Default: profile.SSOStartURL,
 					},
 				},
 				{
-					Name: "ssoRegion",
-					Prompt: &survey.Select{
-						Message: "New SSO region:",
-						Options: regions,
-						Default: section.Key("sso_region").Value(),
-					},
+					Name:   "ssoRegion",
+					Prompt: regionSelect("New SSO region:", profile.SSORegion),
 				},
 				{
 					Name: "ssoAccountID",
 					Prompt: &survey.Input{
 						Message: "New AWS Account ID:",
-						Default: section.Key("sso_account_id").Value(),
-					},
-					Validate: func(val interface{}) error {
-						str, _ := val.(string)
-						if len(str) != 12 {
-							return fmt.Errorf("account ID must be 12 digits")
-						}
-						return nil
+						Default: profile.SSOAccountID,
 					},
+					Validate: validateAccountID,
 				},
 				{
 					Name: "ssoRoleName",
 					Prompt: &survey.Input{
 						Message: "New SSO Role name:",
-						Default: section.Key("sso_role_name").Value(),
+						Default: profile.SSORoleName,
 					},
 				},
 			}
@@ -371,20 +522,113 @@ var configEditCmd = &cobra.Command{
 				SSORoleName  string
 			}{}
 
-			survey.Ask(ssoQuestions, &answers)
-			section.Key("sso_start_url").SetValue(answers.SSOStartURL)
-			section.Key("sso_region").SetValue(answers.SSORegion)
-			section.Key("sso_account_id").SetValue(answers.SSOAccountID)
-			section.Key("sso_role_name").SetValue(answers.SSORoleName)
+			if err := ask(ssoQuestions, &answers); err != nil {
+				return nil, err
+			}
+			apply = func(store *profiles.Store) error {
+				store.SetConfig(selectedProfile, "sso_start_url", answers.SSOStartURL)
+				store.SetConfig(selectedProfile, "sso_region", answers.SSORegion)
+				store.SetConfig(selectedProfile, "sso_account_id", answers.SSOAccountID)
+				store.SetConfig(selectedProfile, "sso_role_name", answers.SSORoleName)
+				return nil
+			}
+
+		case "SSO Account & Role", "Move to an SSO session":
+			session, isNew, err := askSSOSession(store, profile.SSORegion, profile.SSOSession)
+			if err != nil {
+				return nil, err
+			}
+
+			ssoQuestions := []*survey.Question{
+				{
+					Name: "ssoAccountID",
+					Prompt: &survey.Input{
+						Message: "AWS Account ID:",
+						Default: profile.SSOAccountID,
+					},
+					Validate: validateAccountID,
+				},
+				{
+					Name: "ssoRoleName",
+					Prompt: &survey.Input{
+						Message: "SSO Role name:",
+						Default: profile.SSORoleName,
+					},
+					Validate: survey.Required,
+				},
+			}
+
+			answers := struct {
+				SSOAccountID string
+				SSORoleName  string
+			}{}
+
+			if err := ask(ssoQuestions, &answers); err != nil {
+				return nil, err
+			}
+			apply = func(store *profiles.Store) error {
+				if isNew {
+					store.PutSSOSession(session)
+				}
+				// Session settings live in the shared block, not the profile
+				store.DeleteConfigKey(selectedProfile, "sso_start_url")
+				store.DeleteConfigKey(selectedProfile, "sso_region")
+				store.SetConfig(selectedProfile, "sso_session", session.Name)
+				store.SetConfig(selectedProfile, "sso_account_id", answers.SSOAccountID)
+				store.SetConfig(selectedProfile, "sso_role_name", answers.SSORoleName)
+				return nil
+			}
+
+		case "SSO Session":
+			session, ok := store.GetSSOSession(profile.SSOSession)
+			if !ok {
+				return nil, output.Errorf(output.CodeNotFound, "SSO session '%s' is not defined in %s", profile.SSOSession, store.Paths.Config)
+			}
+			if users := store.ProfilesUsingSSOSession(session.Name); len(users) > 1 {
+				fmt.Fprintf(cmd.ErrOrStderr(), "ℹ️  Session '%s' is shared by %d profiles; changes apply to all of them\n", session.Name, len(users))
+			}
+
+			if err := askSSOSessionSettings(store, session, false); err != nil {
+				return nil, err
+			}
+			apply = func(store *profiles.Store) error {
+				store.PutSSOSession(session)
+				return nil
+			}
+
+		case "Role Settings":
+			settings, err := askRoleSettings(store, profile.Type, profile)
+			if err != nil {
+				return nil, err
+			}
+			apply = func(store *profiles.Store) error {
+				applySettings(store, selectedProfile, settings)
+				_, err := store.RoleChain(selectedProfile)
+				return err
+			}
+
+		case "Credential Process":
+			var process string
+			err := askOne(&survey.Input{
+				Message: "Credential process command:",
+				Default: profile.CredentialProcess,
+				Help:    "Command that prints credentials as JSON, e.g. aws-vault exec dev --json",
+			}, &process, survey.WithValidator(survey.Required))
+			if err != nil {
+				return nil, err
+			}
+			apply = func(store *profiles.Store) error {
+				store.SetConfig(selectedProfile, "credential_process", process)
+				return nil
+			}
 
 		case "Access Keys":
-			credSection := creds.Section(selectedProfile)
 			accessKeyQuestions := []*survey.Question{
 				{
 					Name: "accessKeyID",
 					Prompt: &survey.Input{
 						Message: "New AWS Access Key ID:",
-						Default: credSection.Key("aws_access_key_id").Value(),
+						Default: profile.AccessKeyID,
 					},
 				},
 				{
@@ -400,25 +644,34 @@ var configEditCmd = &cobra.Command{
 				SecretAccessKey string
 			}{}
 
-			survey.Ask(accessKeyQuestions, &answers)
-			credSection.Key("aws_access_key_id").SetValue(answers.AccessKeyID)
-			if answers.SecretAccessKey != "" {
-				credSection.Key("aws_secret_access_key").SetValue(answers.SecretAccessKey)
+			if err := ask(accessKeyQuestions, &answers); err != nil {
+				return nil, err
+			}
+			apply = func(store *profiles.Store) error {
+				store.SetCredential(selectedProfile, "aws_access_key_id", answers.AccessKeyID)
+				if answers.SecretAccessKey != "" {
+					store.SetCredential(selectedProfile, "aws_secret_access_key", answers.SecretAccessKey)
+				}
+				return nil
 			}
 		}
 
-		if err := cfg.SaveTo(configPath); err != nil {
-			log.Fatalf("Failed to write config file: %v", err)
+		if apply == nil {
+			return cancelled()
 		}
-		if err := creds.SaveTo(credPath); err != nil {
-			log.Fatalf("Failed to write credentials file: %v", err)
+		if err := updateStore(apply); err != nil {
+			return nil, fmt.Errorf("failed to save profile: %w", err)
 		}
 
-		fmt.Printf("✨ Profile '%s' updated successfully!\n", selectedProfile)
-	},
+		return &profileResult{Action: "updated", Profile: selectedProfile}, nil
+	}),
 }
 
 func init() {
+	addFlags.register(configAddCmd, "Profile name")
+	editFlags.register(configEditCmd, "Profile to edit (or pass it as an argument)")
+	configLsCmd.Flags().StringArrayVar(&lsFilters, "filter", nil, "Filter by name, type or region glob, e.g. type=sso or 'name=prod-*'")
+
 	configCmd.AddCommand(configLsCmd)
 	configCmd.AddCommand(configAddCmd)
 	configCmd.AddCommand(configRemoveCmd)
@@ -0,0 +1,303 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/aphexlog/gsd/internal/output"
+	"github.com/aphexlog/gsd/internal/profiles"
+	"github.com/aphexlog/gsd/internal/ssoaccounts"
+	"github.com/aphexlog/gsd/internal/ssocache"
+	"github.com/aphexlog/gsd/internal/state"
+	"github.com/spf13/cobra"
+)
+
+// defaultDiscoverTemplate names discovered profiles, e.g. "prod-api-AdministratorAccess".
+const defaultDiscoverTemplate = "{{.AccountName | slug}}-{{.RoleName}}"
+
+var (
+	discoverSession  string
+	discoverTemplate string
+	discoverRegion   string
+	discoverAll      bool
+
+	ssoEndpointURL string
+)
+
+var configDiscoverCmd = &cobra.Command{
+	Use:   "discover",
+	Short: "Create profiles for the accounts and roles an SSO session can reach",
+	Long: `List every account and role available through an SSO session, using the
+token cached by 'aws sso login', and create a profile for the ones you pick.
+Profile names come from a Go template with the fields .AccountName,
+.AccountID, .RoleName, .Session and .Email and the functions slug, lower and
+upper.
+
+  gsd config discover --sso-session corp
+  gsd config discover --sso-session corp --all --template '{{.AccountID}}-{{.RoleName | lower}}'`,
+	Args: cobra.NoArgs,
+	RunE: runResult(func(cmd *cobra.Command, args []string) (any, error) {
+		nameTemplate, err := parseNameTemplate(discoverTemplate)
+		if err != nil {
+			return nil, err
+		}
+		if discoverRegion != "" {
+			if err := validateRegion(discoverRegion); err != nil {
+				return nil, output.Errorf(output.CodeInvalidArgument, "--region: %w", err)
+			}
+		}
+
+		store, err := loadStore()
+		if err != nil {
+			return nil, err
+		}
+		var sessionArgs []string
+		if discoverSession != "" {
+			sessionArgs = []string{discoverSession}
+		} else if err := requireTerminal("--sso-session"); err != nil {
+			return nil, err
+		}
+		name, err := selectSSOSession(store, sessionArgs, "Choose an SSO session to discover:")
+		if err != nil {
+			return nil, err
+		}
+		session, ok := store.GetSSOSession(name)
+		if !ok {
+			return nil, output.Errorf(output.CodeNotFound, "SSO session '%s' not found", name)
+		}
+		region := discoverRegion
+		if region == "" {
+			region = session.Region
+		}
+
+		client, err := ssoClient(session)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(os.Stderr, "🔍 Listing accounts and roles for SSO session '%s'...\n", session.Name)
+		assignments, err := client.Assignments(context.TODO())
+		if err != nil {
+			return nil, ssoError(session, err)
+		}
+
+		// Remember the account names so `gsd prompt` can show them.
+		names := make(map[string]string)
+		for _, a := range assignments {
+			names[a.AccountID] = a.AccountName
+		}
+		_ = state.LearnAccountNames(names)
+
+		result, err := planDiscover(store, session.Name, region, nameTemplate, assignments)
+		if err != nil {
+			return nil, err
+		}
+		if len(result.Created) == 0 {
+			return result, nil
+		}
+
+		if !discoverAll {
+			selected, err := selectDiscovered(result.Created)
+			if err != nil {
+				return nil, err
+			}
+			if len(selected) == 0 {
+				return cancelled()
+			}
+			result.Created = selected
+		}
+
+		err = updateStore(func(store *profiles.Store) error {
+			for _, p := range result.Created {
+				if store.Has(p.Name) {
+					return output.Errorf(output.CodeConflict, "profile '%s' already exists", p.Name)
+				}
+				store.SetConfig(p.Name, "region", p.Region)
+				store.SetConfig(p.Name, "sso_session", session.Name)
+				store.SetConfig(p.Name, "sso_account_id", p.AccountID)
+				store.SetConfig(p.Name, "sso_role_name", p.RoleName)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to save profiles: %w", err)
+		}
+		return result, nil
+	}),
+}
+
+// ssoClient returns a portal API client using the session's cached token.
+func ssoClient(session *profiles.SSOSession) (*ssoaccounts.Client, error) {
+	token, err := ssocache.Load(session.Name)
+	if err != nil || !token.Valid() {
+		return nil, output.Errorf(output.CodeUnauthenticated,
+			"no usable token for SSO session '%s' (%s); run 'aws sso login --sso-session %s'",
+			session.Name, ssocache.Status(token, err), session.Name)
+	}
+	endpoint := ssoEndpointURL
+	if endpoint == "" {
+		endpoint = os.Getenv(ssoaccounts.EndpointEnv)
+	}
+	return ssoaccounts.New(session.Region, endpoint, token.AccessToken), nil
+}
+
+// ssoError gives a failed portal call its error code.
+func ssoError(session *profiles.SSOSession, err error) error {
+	if ssoaccounts.IsUnauthorized(err) {
+		return output.Errorf(output.CodeUnauthenticated,
+			"the token for SSO session '%s' was rejected; run 'aws sso login --sso-session %s'", session.Name, session.Name)
+	}
+	return output.Errorf(output.CodeAWS, "failed to list SSO accounts: %w", err)
+}
+
+var slugPattern = regexp.MustCompile(`[^a-z0-9]+`)
+
+// slug turns an account name such as "Prod API (EU)" into "prod-api-eu".
+func slug(s string) string {
+	return strings.Trim(slugPattern.ReplaceAllString(strings.ToLower(s), "-"), "-")
+}
+
+func parseNameTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("name").Option("missingkey=error").Funcs(template.FuncMap{
+		"slug":  slug,
+		"lower": strings.ToLower,
+		"upper": strings.ToUpper,
+	}).Parse(text)
+	if err != nil {
+		return nil, output.Errorf(output.CodeInvalidArgument, "invalid --template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// discoveredProfile is a profile for one account and role.
+type discoveredProfile struct {
+	Name        string `json:"name" yaml:"name"`
+	AccountID   string `json:"account_id" yaml:"account_id"`
+	AccountName string `json:"account_name" yaml:"account_name"`
+	RoleName    string `json:"role_name" yaml:"role_name"`
+	Region      string `json:"region,omitempty" yaml:"region,omitempty"`
+	Reason      string `json:"reason,omitempty" yaml:"reason,omitempty"`
+}
+
+// planDiscover names a profile for each assignment and sorts them into the
+// ones to create, the ones already configured, and name clashes.
+func planDiscover(store *profiles.Store, session, region string, tmpl *template.Template, assignments []ssoaccounts.Assignment) (*discoverResult, error) {
+	existing := make(map[string]string)
+	for _, p := range store.Profiles() {
+		if p.SSOSession == session {
+			existing[p.SSOAccountID+"/"+p.SSORoleName] = p.Name
+		}
+	}
+
+	result := &discoverResult{Session: session}
+	named := make(map[string]string)
+	for _, a := range assignments {
+		var buf bytes.Buffer
+		err := tmpl.Execute(&buf, map[string]string{
+			"AccountName": a.AccountName,
+			"AccountID":   a.AccountID,
+			"RoleName":    a.RoleName,
+			"Session":     session,
+			"Email":       a.Email,
+		})
+		if err != nil {
+			return nil, output.Errorf(output.CodeInvalidArgument, "invalid --template: %w", err)
+		}
+		p := discoveredProfile{
+			Name:        buf.String(),
+			AccountID:   a.AccountID,
+			AccountName: a.AccountName,
+			RoleName:    a.RoleName,
+			Region:      region,
+		}
+		if err := validateProfileName(p.Name); err != nil {
+			return nil, output.Errorf(output.CodeInvalidArgument, "--template gives '%s' for %s/%s", p.Name, a.AccountName, a.RoleName)
+		}
+		who := a.AccountName + "/" + a.RoleName
+		if prev, ok := named[p.Name]; ok {
+			return nil, output.Errorf(output.CodeInvalidArgument, "--template gives '%s' to both %s and %s", p.Name, prev, who)
+		}
+		named[p.Name] = who
+
+		switch name, ok := existing[a.AccountID+"/"+a.RoleName]; {
+		case ok:
+			p.Name, p.Region = name, ""
+			result.Existing = append(result.Existing, p)
+		case store.Has(p.Name):
+			p.Reason = "a different profile already has this name"
+			result.Conflicts = append(result.Conflicts, p)
+		default:
+			result.Created = append(result.Created, p)
+		}
+	}
+	return result, nil
+}
+
+// selectDiscovered asks which of the new profiles to create.
+func selectDiscovered(candidates []discoveredProfile) ([]discoveredProfile, error) {
+	options := make([]string, len(candidates))
+	for i, p := range candidates {
+		options[i] = fmt.Sprintf("%s (%s %s / %s)", p.Name, p.AccountName, p.AccountID, p.RoleName)
+	}
+	var picked []int
+	err := askFlag("--all", &survey.MultiSelect{
+		Message:  "Choose the profiles to create:",
+		Options:  options,
+		Default:  options,
+		PageSize: 15,
+	}, &picked)
+	if err != nil {
+		return nil, err
+	}
+	selected := make([]discoveredProfile, 0, len(picked))
+	for _, i := range picked {
+		selected = append(selected, candidates[i])
+	}
+	return selected, nil
+}
+
+// discoverResult is the result of `gsd config discover`.
+type discoverResult struct {
+	Session   string              `json:"sso_session" yaml:"sso_session"`
+	Created   []discoveredProfile `json:"created" yaml:"created"`
+	Existing  []discoveredProfile `json:"existing" yaml:"existing"`
+	Conflicts []discoveredProfile `json:"conflicts" yaml:"conflicts"`
+}
+
+func (r *discoverResult) Text(w io.Writer) {
+	if len(r.Created) == 0 && len(r.Conflicts) == 0 {
+		fmt.Fprintf(w, "✨ Every role in SSO session '%s' already has a profile (%d)\n", r.Session, len(r.Existing))
+		return
+	}
+	if len(r.Created) > 0 {
+		fmt.Fprintf(w, "✨ Created %d profile(s) from SSO session '%s':\n", len(r.Created), r.Session)
+		for _, p := range r.Created {
+			fmt.Fprintf(w, "   + %s (%s / %s)\n", p.Name, p.AccountName, p.RoleName)
+		}
+	}
+	if len(r.Conflicts) > 0 {
+		fmt.Fprintf(w, "⚠️  Skipped %d role(s) whose profile name is taken:\n", len(r.Conflicts))
+		for _, p := range r.Conflicts {
+			fmt.Fprintf(w, "   ! %s (%s / %s)\n", p.Name, p.AccountName, p.RoleName)
+		}
+	}
+	if len(r.Existing) > 0 {
+		fmt.Fprintf(w, "   %d role(s) already had a profile\n", len(r.Existing))
+	}
+}
+
+func init() {
+	configDiscoverCmd.Flags().StringVar(&discoverSession, "sso-session", "", "SSO session to discover accounts through")
+	configDiscoverCmd.Flags().StringVar(&discoverTemplate, "template", defaultDiscoverTemplate, "Go template for profile names")
+	configDiscoverCmd.Flags().StringVar(&discoverRegion, "region", "", "Region for the new profiles (default: the session's region)")
+	configDiscoverCmd.Flags().BoolVar(&discoverAll, "all", false, "Create every new profile without asking")
+	configDiscoverCmd.Flags().StringVar(&ssoEndpointURL, "endpoint-url", "", "SSO portal endpoint (default: $"+ssoaccounts.EndpointEnv+" or the AWS endpoint)")
+
+	configCmd.AddCommand(configDiscoverCmd)
+}
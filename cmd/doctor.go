@@ -0,0 +1,407 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aphexlog/gsd/internal/inifile"
+	"github.com/aphexlog/gsd/internal/profiles"
+	"github.com/aphexlog/gsd/internal/shell"
+	"github.com/aphexlog/gsd/internal/ssocache"
+	"github.com/aphexlog/gsd/internal/state"
+	"github.com/spf13/cobra"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the local AWS setup for common problems",
+	Long: `Run a series of checks on the AWS CLI, the config and credentials files,
+the profiles in them, cached SSO tokens and the environment, and print a
+fix for anything that needs attention. gsd exits non-zero if a check fails.`,
+	Args: cobra.NoArgs,
+	RunE: runResult(func(cmd *cobra.Command, args []string) (any, error) {
+		report := &doctorReport{}
+		report.add(checkAWSCLI())
+
+		paths := awsPaths()
+		report.add(checkAWSFile("config file", paths.Config, 0022))
+		report.add(checkAWSFile("credentials file", paths.Credentials, 0077))
+
+		store, err := profiles.Load(paths)
+		if err != nil {
+			// The file checks above already explain what is wrong.
+			return report, nil
+		}
+		report.add(checkDuplicateProfiles(store))
+		report.add(checkSourceProfiles(store))
+		report.add(checkSSOSessionRefs(store))
+		report.add(checkSSOTokens(store))
+		report.add(checkCurrentProfile(store))
+		report.add(checkEnvironment(store))
+		report.add(checkShellIntegration())
+		return report, nil
+	}),
+}
+
+// Check statuses.
+const (
+	checkPass = "pass"
+	checkWarn = "warn"
+	checkFail = "fail"
+)
+
+// doctorCheck is the outcome of one check. Details list the individual
+// problems, and Fix says what to do about them.
+type doctorCheck struct {
+	Name    string   `json:"name" yaml:"name"`
+	Status  string   `json:"status" yaml:"status"`
+	Message string   `json:"message" yaml:"message"`
+	Details []string `json:"details,omitempty" yaml:"details,omitempty"`
+	Fix     string   `json:"fix,omitempty" yaml:"fix,omitempty"`
+}
+
+func pass(name, format string, args ...any) doctorCheck {
+	return doctorCheck{Name: name, Status: checkPass, Message: fmt.Sprintf(format, args...)}
+}
+
+// checkAWSCLI looks for the aws binary that gsd login runs.
+func checkAWSCLI() doctorCheck {
+	const name = "AWS CLI"
+	path, err := exec.LookPath("aws")
+	if err != nil {
+		return doctorCheck{Name: name, Status: checkWarn,
+			Message: "aws is not on PATH, so 'gsd login' won't work",
+			Fix:     "install AWS CLI v2: https://docs.aws.amazon.com/cli/latest/userguide/getting-started-install.html"}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, path, "--version").CombinedOutput()
+	if err != nil {
+		return doctorCheck{Name: name, Status: checkWarn,
+			Message: fmt.Sprintf("%s does not run: %v", path, err),
+			Fix:     "reinstall AWS CLI v2"}
+	}
+	version := strings.Fields(string(out))
+	if len(version) == 0 {
+		return doctorCheck{Name: name, Status: checkWarn, Message: fmt.Sprintf("%s printed no version", path)}
+	}
+	if strings.HasPrefix(version[0], "aws-cli/1.") {
+		return doctorCheck{Name: name, Status: checkWarn,
+			Message: fmt.Sprintf("%s at %s does not support sso-session profiles", version[0], path),
+			Fix:     "upgrade to AWS CLI v2"}
+	}
+	return pass(name, "%s at %s", version[0], path)
+}
+
+// checkAWSFile parses one of the AWS files and checks that no one but the
+// owner can write it, or for the credentials file, read it. forbidden holds
+// the permission bits that must not be set.
+func checkAWSFile(name, path string, forbidden fs.FileMode) doctorCheck {
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return pass(name, "%s does not exist", path)
+	}
+	if err != nil {
+		return doctorCheck{Name: name, Status: checkFail, Message: err.Error()}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return doctorCheck{Name: name, Status: checkFail, Message: err.Error()}
+	}
+	if _, err := inifile.Parse(data); err != nil {
+		return doctorCheck{Name: name, Status: checkFail,
+			Message: fmt.Sprintf("%s does not parse: %v", path, err),
+			Fix:     "fix the line by hand, or restore an earlier version with 'gsd config undo'"}
+	}
+
+	if runtime.GOOS != "windows" && info.Mode().Perm()&forbidden != 0 {
+		return doctorCheck{Name: name, Status: checkFail,
+			Message: fmt.Sprintf("%s has unsafe permissions %04o", path, info.Mode().Perm()),
+			Fix:     fmt.Sprintf("chmod 600 %s", path)}
+	}
+	return pass(name, "%s parses, permissions %04o", path, info.Mode().Perm())
+}
+
+// checkDuplicateProfiles lists the profiles defined in both files, with
+// any key set in both. The credentials file wins for those keys, which is
+// rarely what was intended.
+func checkDuplicateProfiles(store *profiles.Store) doctorCheck {
+	const name = "duplicate profiles"
+	var details []string
+	overlapping := 0
+	for _, p := range store.Profiles() {
+		if p.Source != profiles.SourceConfig|profiles.SourceCredentials {
+			continue
+		}
+		config := store.Settings(profiles.Target{Kind: profiles.ProfileSection, Name: p.Name})
+		var both []string
+		for key := range store.Credentials(p.Name) {
+			if _, ok := config[key]; ok {
+				both = append(both, key)
+			}
+		}
+		if len(both) == 0 {
+			details = append(details, fmt.Sprintf("%s: no key set in both", p.Name))
+			continue
+		}
+		sort.Strings(both)
+		details = append(details, fmt.Sprintf("%s: %s set in both", p.Name, strings.Join(both, ", ")))
+		overlapping++
+	}
+	switch {
+	case len(details) == 0:
+		return pass(name, "no profile is defined in both files")
+	case overlapping == 0:
+		check := pass(name, "%d profile(s) are defined in both files, with no key set twice", len(details))
+		check.Details = details
+		return check
+	}
+	return doctorCheck{Name: name, Status: checkWarn,
+		Message: fmt.Sprintf("%d profile(s) are defined in both files, %d with keys set in both; the credentials file wins", len(details), overlapping),
+		Details: details,
+		Fix:     "keep each key in one file ('gsd config unset' removes it from the file it is in)"}
+}
+
+// checkSourceProfiles follows every role chain.
+func checkSourceProfiles(store *profiles.Store) doctorCheck {
+	const name = "source profiles"
+	var details []string
+	cycles := make(map[string]bool)
+	roles := 0
+	for _, p := range store.Profiles() {
+		if p.SourceProfile == "" {
+			continue
+		}
+		roles++
+		_, err := store.RoleChain(p.Name)
+		var cycle *profiles.CycleError
+		if errors.As(err, &cycle) {
+			// Report each loop once, not once per profile on it.
+			members := append([]string(nil), cycle.Chain...)
+			sort.Strings(members)
+			key := strings.Join(members, " ")
+			if cycles[key] {
+				continue
+			}
+			cycles[key] = true
+		}
+		if err != nil {
+			details = append(details, err.Error())
+		}
+	}
+	if len(details) == 0 {
+		return pass(name, "%d role chain(s) resolve", roles)
+	}
+	return doctorCheck{Name: name, Status: checkFail,
+		Message: fmt.Sprintf("%d broken role chain(s)", len(details)),
+		Details: details,
+		Fix:     "point source_profile at an existing profile with 'gsd config set <profile> source_profile <name>'"}
+}
+
+// checkSSOSessionRefs finds profiles naming an sso-session that is missing.
+func checkSSOSessionRefs(store *profiles.Store) doctorCheck {
+	const name = "SSO sessions"
+	var details []string
+	for _, p := range store.Profiles() {
+		if p.SSOSession == "" {
+			continue
+		}
+		if _, ok := store.GetSSOSession(p.SSOSession); !ok {
+			details = append(details, fmt.Sprintf("%s uses sso_session '%s'", p.Name, p.SSOSession))
+		}
+	}
+	if len(details) == 0 {
+		return pass(name, "every sso_session is defined")
+	}
+	return doctorCheck{Name: name, Status: checkFail,
+		Message: fmt.Sprintf("%d profile(s) use an undefined SSO session", len(details)),
+		Details: details,
+		Fix:     "create the session with 'gsd sso add'"}
+}
+
+// checkSSOTokens reports expired tokens of sessions and legacy start URLs
+// that profiles use.
+func checkSSOTokens(store *profiles.Store) doctorCheck {
+	const name = "SSO tokens"
+	keys := make(map[string]bool)
+	for _, p := range store.Profiles() {
+		switch {
+		case p.SSOSession != "":
+			keys[p.SSOSession] = true
+		case p.SSOStartURL != "":
+			keys[p.SSOStartURL] = true
+		}
+	}
+
+	var expired, fixes []string
+	valid := 0
+	for key := range keys {
+		token, err := ssocache.Load(key)
+		if err != nil {
+			continue
+		}
+		if token.Valid() {
+			valid++
+			continue
+		}
+		expired = append(expired, key)
+	}
+	sort.Strings(expired)
+	if len(expired) == 0 {
+		return pass(name, "%d valid cached token(s), none expired", valid)
+	}
+	for _, key := range expired {
+		if _, ok := store.GetSSOSession(key); ok {
+			fixes = append(fixes, "aws sso login --sso-session "+key)
+		} else {
+			fixes = append(fixes, "aws sso login --profile <a profile using "+key+">")
+		}
+	}
+	return doctorCheck{Name: name, Status: checkWarn,
+		Message: fmt.Sprintf("%d cached token(s) expired", len(expired)),
+		Details: expired,
+		Fix:     strings.Join(fixes, "; ")}
+}
+
+// checkCurrentProfile checks the profile remembered by gsd switch --global.
+func checkCurrentProfile(store *profiles.Store) doctorCheck {
+	const name = "current profile"
+	current := state.GlobalProfile()
+	if current == "" {
+		return pass(name, "no profile switched with --global")
+	}
+	if !store.Has(current) {
+		return doctorCheck{Name: name, Status: checkFail,
+			Message: fmt.Sprintf("'%s', chosen with gsd switch --global, no longer exists", current),
+			Fix:     "run 'gsd switch --global'"}
+	}
+	return pass(name, "'%s'", current)
+}
+
+// checkEnvironment finds AWS_* variables that take precedence over the
+// profile gsd switched to.
+func checkEnvironment(store *profiles.Store) doctorCheck {
+	const name = "environment"
+	var details, fixes []string
+	status := checkPass
+
+	var keys []string
+	for _, key := range []string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN"} {
+		if os.Getenv(key) != "" {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) > 0 {
+		status = checkWarn
+		details = append(details, strings.Join(keys, ", ")+" override the credentials of every profile")
+		fixes = append(fixes, "unset "+strings.Join(keys, " "))
+	}
+
+	profile := os.Getenv("AWS_PROFILE")
+	if profile != "" && !store.Has(profile) {
+		status = checkFail
+		details = append(details, fmt.Sprintf("AWS_PROFILE names '%s', which does not exist", profile))
+		fixes = append(fixes, "run 'gsd switch' or unset AWS_PROFILE")
+	}
+	if current := state.GlobalProfile(); current != "" && profile != "" && current != profile {
+		if status == checkPass {
+			status = checkWarn
+		}
+		details = append(details, fmt.Sprintf("AWS_PROFILE=%s overrides '%s' chosen with gsd switch --global", profile, current))
+	}
+	if os.Getenv("AWS_DEFAULT_PROFILE") != "" && profile == "" {
+		if status == checkPass {
+			status = checkWarn
+		}
+		details = append(details, "AWS_DEFAULT_PROFILE is only read by some tools")
+		fixes = append(fixes, "use AWS_PROFILE instead")
+	}
+
+	var region string
+	if p, ok := store.Get(activeProfile()); ok {
+		region = p.Region
+	}
+	for _, key := range []string{"AWS_REGION", "AWS_DEFAULT_REGION"} {
+		if v := os.Getenv(key); v != "" && region != "" && v != region {
+			if status == checkPass {
+				status = checkWarn
+			}
+			details = append(details, fmt.Sprintf("%s=%s overrides region %s of profile '%s'", key, v, region, activeProfile()))
+			fixes = append(fixes, "unset "+key)
+			break
+		}
+	}
+
+	if status == checkPass {
+		return pass(name, "no AWS_* variable overrides profile '%s'", activeProfile())
+	}
+	return doctorCheck{Name: name, Status: status,
+		Message: fmt.Sprintf("%d variable(s) override the active profile", len(details)),
+		Details: details,
+		Fix:     strings.Join(fixes, "; ")}
+}
+
+// checkShellIntegration reports whether gsd runs through the shell-init
+// wrapper, which per-shell switching needs.
+func checkShellIntegration() doctorCheck {
+	const name = "shell integration"
+	if shell.Active() {
+		return pass(name, "active (%s)", os.Getenv(shell.ShellVar))
+	}
+	return doctorCheck{Name: name, Status: checkWarn,
+		Message: "not active, so 'gsd switch' only works with --global",
+		Fix:     "add 'eval \"$(gsd shell-init bash)\"' (or zsh, fish, powershell) to your shell's startup file"}
+}
+
+// doctorReport is the result of `gsd doctor`.
+type doctorReport struct {
+	Checks []doctorCheck `json:"checks" yaml:"checks"`
+}
+
+func (r *doctorReport) add(c doctorCheck) {
+	r.Checks = append(r.Checks, c)
+}
+
+func (r *doctorReport) count(status string) int {
+	n := 0
+	for _, c := range r.Checks {
+		if c.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// Failed makes gsd exit non-zero when a check failed.
+func (r *doctorReport) Failed() bool {
+	return r.count(checkFail) > 0
+}
+
+func (r *doctorReport) Text(w io.Writer) {
+	icons := map[string]string{checkPass: "✅", checkWarn: "⚠️ ", checkFail: "❌"}
+	for _, c := range r.Checks {
+		fmt.Fprintf(w, "%s %s: %s\n", icons[c.Status], c.Name, c.Message)
+		for _, d := range c.Details {
+			fmt.Fprintf(w, "     - %s\n", d)
+		}
+		if c.Fix != "" {
+			fmt.Fprintf(w, "   💡 %s\n", c.Fix)
+		}
+	}
+	fmt.Fprintf(w, "\n🩺 %d passed, %d warning(s), %d failed\n", r.count(checkPass), r.count(checkWarn), r.count(checkFail))
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+}
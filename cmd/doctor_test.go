@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCheckDuplicateProfiles(t *testing.T) {
+	cases := []struct {
+		name        string
+		credentials map[string]map[string]string
+		status      string
+		details     []string
+	}{
+		{"config only", nil, checkPass, nil},
+		{"split across files", map[string]map[string]string{
+			"dev": {"aws_access_key_id": "AKIA1"},
+		}, checkPass, []string{"dev: no key set in both"}},
+		{"key in both", map[string]map[string]string{
+			"dev": {"aws_access_key_id": "AKIA1", "region": "us-west-2"},
+			"ci":  {"aws_access_key_id": "AKIA2"},
+		}, checkWarn, []string{"ci: no key set in both", "dev: region set in both"}},
+		{"credentials only", map[string]map[string]string{
+			"deploy": {"aws_access_key_id": "AKIA3"},
+		}, checkPass, nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := loadTestStore(t, "[profile dev]\nregion = us-east-1\n\n[profile ci]\nregion = eu-west-1\n")
+			for profile, values := range tc.credentials {
+				for key, value := range values {
+					store.SetCredential(profile, key, value)
+				}
+			}
+			check := checkDuplicateProfiles(store)
+			if check.Status != tc.status || !slices.Equal(check.Details, tc.details) {
+				t.Errorf("got %s %v, want %s %v", check.Status, check.Details, tc.status, tc.details)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/aphexlog/gsd/internal/output"
+	"github.com/aphexlog/gsd/internal/profiles"
+	"github.com/aphexlog/gsd/internal/state"
+	"github.com/spf13/cobra"
+)
+
+var configHistoryCmd = &cobra.Command{
+	Use:   "history",
+	Short: "List recent changes gsd made to the AWS files",
+	RunE: runResult(func(cmd *cobra.Command, args []string) (any, error) {
+		snaps, err := state.Snapshots()
+		if err != nil {
+			return nil, fmt.Errorf("unable to read history: %w", err)
+		}
+
+		entries := historyList{}
+		for _, snap := range snaps {
+			entries = append(entries, historyEntry{
+				ID:       snap.ID,
+				Time:     snap.Time,
+				Command:  snap.Command,
+				Summary:  snap.Summary,
+				Restores: snap.Restores,
+			})
+		}
+		return entries, nil
+	}),
+}
+
+// historyEntry describes a snapshot without the file contents it holds.
+type historyEntry struct {
+	ID       string    `json:"id" yaml:"id"`
+	Time     time.Time `json:"time" yaml:"time"`
+	Command  string    `json:"command" yaml:"command"`
+	Summary  []string  `json:"summary" yaml:"summary"`
+	Restores string    `json:"restores,omitempty" yaml:"restores,omitempty"`
+}
+
+// historyList is the result of `gsd config history`, newest first.
+type historyList []historyEntry
+
+func (entries historyList) Text(w io.Writer) {
+	if len(entries) == 0 {
+		fmt.Fprintln(w, "🤖 No changes recorded yet")
+		return
+	}
+
+	fmt.Fprintln(w, "✨ Recent changes (newest first):")
+	for _, entry := range entries {
+		fmt.Fprintf(w, "   %s  %s  %s\n", entry.ID, entry.Time.Local().Format("2006-01-02 15:04:05"), entry.Command)
+		for _, line := range entry.Summary {
+			fmt.Fprintf(w, "      %s\n", line)
+		}
+	}
+}
+
+var configUndoCmd = &cobra.Command{
+	Use:   "undo",
+	Short: "Roll back the most recent change gsd made",
+	RunE: runResult(func(cmd *cobra.Command, args []string) (any, error) {
+		snap, err := state.UndoTarget()
+		if err != nil {
+			return nil, output.Errorf(output.CodeNotFound, "%w", err)
+		}
+		return restoreSnapshot(snap)
+	}),
+}
+
+var configRestoreCmd = &cobra.Command{
+	Use:   "restore <id>",
+	Short: "Restore the AWS files to a snapshot from 'gsd config history'",
+	Args:  cobra.ExactArgs(1),
+	RunE: runResult(func(cmd *cobra.Command, args []string) (any, error) {
+		snap, err := state.LoadSnapshot(args[0])
+		if err != nil {
+			return nil, output.Errorf(output.CodeNotFound, "%w", err)
+		}
+		return restoreSnapshot(snap)
+	}),
+}
+
+// restoreResult is the result of `gsd config undo` and `gsd config restore`.
+type restoreResult struct {
+	Snapshot string `json:"snapshot" yaml:"snapshot"`
+	Command  string `json:"command" yaml:"command"`
+}
+
+func (r *restoreResult) Text(w io.Writer) {
+	fmt.Fprintf(w, "✨ Restored AWS files to before '%s' (%s)\n", r.Command, r.Snapshot)
+}
+
+// restoreSnapshot writes a snapshot back to the files it was taken from. The
+// state being replaced is itself recorded, so a restore can be undone too.
+func restoreSnapshot(snap *state.Snapshot) (*restoreResult, error) {
+	paths := profiles.Paths{Config: snap.ConfigPath, Credentials: snap.CredentialsPath}
+	err := profiles.Update(paths, func(store *profiles.Store) error {
+		if err := store.Replace([]byte(snap.Config), []byte(snap.Credentials)); err != nil {
+			return err
+		}
+		return saveWithSnapshot(store, snap.ID)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to restore snapshot: %w", err)
+	}
+
+	return &restoreResult{Snapshot: snap.ID, Command: snap.Command}, nil
+}
+
+func init() {
+	configCmd.AddCommand(configHistoryCmd)
+	configCmd.AddCommand(configUndoCmd)
+	configCmd.AddCommand(configRestoreCmd)
+}
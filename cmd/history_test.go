@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"os"
+	"testing"
+
+	"github.com/aphexlog/gsd/internal/profiles"
+	"github.com/aphexlog/gsd/internal/state"
+)
+
+// useTestFiles points gsd at temporary AWS files holding config, and at a
+// temporary state directory.
+func useTestFiles(t *testing.T, config string) profiles.Paths {
+	t.Helper()
+	t.Setenv("GSD_STATE_DIR", t.TempDir())
+	paths := loadTestStore(t, config).Paths
+	configFileFlag, credentialsFileFlag = paths.Config, paths.Credentials
+	t.Cleanup(func() { configFileFlag, credentialsFileFlag = "", "" })
+	return paths
+}
+
+func readConfig(t *testing.T, paths profiles.Paths) string {
+	t.Helper()
+	data, err := os.ReadFile(paths.Config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func undo(t *testing.T) {
+	t.Helper()
+	snap, err := state.UndoTarget()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := restoreSnapshot(snap); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func setRegion(t *testing.T, region string) {
+	t.Helper()
+	err := updateStore(func(store *profiles.Store) error {
+		store.SetConfig("dev", "region", region)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+const historyConfig = "[profile dev]\nregion = us-east-1\n"
+
+func TestUndoRoundTrip(t *testing.T) {
+	paths := useTestFiles(t, historyConfig)
+
+	setRegion(t, "eu-west-1")
+	afterFirst := readConfig(t, paths)
+	setRegion(t, "ap-south-1")
+
+	undo(t)
+	if got := readConfig(t, paths); got != afterFirst {
+		t.Errorf("first undo gave:\n%s\nwant:\n%s", got, afterFirst)
+	}
+	undo(t)
+	if got := readConfig(t, paths); got != historyConfig {
+		t.Errorf("second undo gave:\n%s\nwant:\n%s", got, historyConfig)
+	}
+	if _, err := state.UndoTarget(); err == nil {
+		t.Error("expected nothing left to undo")
+	}
+}
+
+func TestUndoAfterExternalEdit(t *testing.T) {
+	paths := useTestFiles(t, historyConfig)
+
+	setRegion(t, "eu-west-1")
+	edited := readConfig(t, paths) + "\n[profile manual]\nregion = us-east-2\n"
+	if err := os.WriteFile(paths.Config, []byte(edited), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Undo goes back to before gsd's change, dropping the edit made since...
+	undo(t)
+	if got := readConfig(t, paths); got != historyConfig {
+		t.Errorf("undo gave:\n%s\nwant:\n%s", got, historyConfig)
+	}
+
+	// ...but the replaced files were recorded, so the edit can be restored.
+	snaps, err := state.Snapshots()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snaps) == 0 || snaps[0].Restores == "" {
+		t.Fatal("undo did not record the files it replaced")
+	}
+	if _, err := restoreSnapshot(snaps[0]); err != nil {
+		t.Fatal(err)
+	}
+	if got := readConfig(t, paths); got != edited {
+		t.Errorf("restore gave:\n%s\nwant:\n%s", got, edited)
+	}
+}
+
+func TestFailedUpdateRecordsNothing(t *testing.T) {
+	paths := useTestFiles(t, historyConfig)
+
+	err := updateStore(func(store *profiles.Store) error {
+		store.SetConfig("dev", "region", "eu-west-1")
+		return os.ErrInvalid
+	})
+	if err == nil {
+		t.Fatal("expected the update to fail")
+	}
+	if got := readConfig(t, paths); got != historyConfig {
+		t.Errorf("failed update wrote:\n%s", got)
+	}
+	if snaps, _ := state.Snapshots(); len(snaps) != 0 {
+		t.Errorf("failed update left %d snapshot(s)", len(snaps))
+	}
+}
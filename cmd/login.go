@@ -2,42 +2,66 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 
+	"github.com/aphexlog/gsd/internal/output"
 	"github.com/spf13/cobra"
 )
 
 var loginCmd = &cobra.Command{
 	Use:   "login",
 	Short: "Logs in to AWS using the current profile",
-	Long:  `Logs in to AWS using the profile set in the AWS_PROFILE environment variable.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		profile := os.Getenv("AWS_PROFILE")
-		if profile == "" {
-			profile = "default"
+	Long: `Logs in to AWS using the current profile: AWS_PROFILE if set, otherwise the
+profile last chosen with 'gsd switch --global', otherwise default.`,
+	RunE: runResult(func(cmd *cobra.Command, args []string) (any, error) {
+		profile := activeProfile()
+		if err := ssoLogin(profile); err != nil {
+			return nil, err
 		}
 
-		_, err := exec.LookPath("aws")
-		if err != nil {
-			fmt.Println("❌ AWS CLI is not installed or not found in PATH.")
-			os.Exit(1)
-		}
+		return &loginResult{Profile: profile, LoggedIn: true}, nil
+	}),
+}
 
-		fmt.Printf("🔐 Logging in with profile '%s'...\n", profile)
+// ssoLogin runs `aws sso login` for a profile, against the files gsd is
+// using.
+func ssoLogin(profile string) error {
+	if _, err := exec.LookPath("aws"); err != nil {
+		return output.Errorf(output.CodeNotFound, "AWS CLI is not installed or not found in PATH")
+	}
 
-		cmdExec := exec.Command("aws", "sso", "login", "--profile", profile)
-		cmdExec.Stdout = os.Stdout
-		cmdExec.Stderr = os.Stderr
-		cmdExec.Stdin = os.Stdin
+	fmt.Fprintf(os.Stderr, "🔐 Logging in with profile '%s'...\n", profile)
 
-		if err := cmdExec.Run(); err != nil {
-			fmt.Printf("❌ Login failed: %v\n", err)
-			os.Exit(1)
-		}
+	// The AWS CLI's own output goes to stderr so that stdout only
+	// carries the result when --output is json or yaml.
+	cmdExec := exec.Command("aws", "sso", "login", "--profile", profile)
+	cmdExec.Stdout = os.Stderr
+	cmdExec.Stderr = os.Stderr
+	cmdExec.Stdin = os.Stdin
+
+	// Point the AWS CLI at the same files gsd is using
+	paths := awsPaths()
+	cmdExec.Env = append(os.Environ(),
+		"AWS_CONFIG_FILE="+paths.Config,
+		"AWS_SHARED_CREDENTIALS_FILE="+paths.Credentials,
+	)
+
+	if err := cmdExec.Run(); err != nil {
+		return output.Errorf(output.CodeAWS, "login failed: %w", err)
+	}
+	return nil
+}
+
+// loginResult is the result of `gsd login`.
+type loginResult struct {
+	Profile  string `json:"profile" yaml:"profile"`
+	LoggedIn bool   `json:"logged_in" yaml:"logged_in"`
+}
 
-		fmt.Println("✅ Login successful.")
-	},
+func (r *loginResult) Text(w io.Writer) {
+	fmt.Fprintln(w, "✅ Login successful.")
 }
 
 func init() {
@@ -2,33 +2,30 @@ package cmd
 
 import (
 	"fmt"
-	"log"
-	"os"
+	"io"
 	"os/exec"
-	"path/filepath"
 	"runtime"
-	"strings"
 
 	"github.com/AlecAivazis/survey/v2"
+	"github.com/aphexlog/gsd/internal/output"
 	"github.com/spf13/cobra"
-	"gopkg.in/ini.v1"
 )
 
 // AWS services and their console URLs
 var awsServices = map[string]string{
-	"Console (Main)":    "https://signin.aws.amazon.com/console",
-	"SSO":              "https://d-{account_id}.awsapps.com/start",  // Will be replaced with actual SSO URL
-	"EC2":              "https://console.aws.amazon.com/ec2/v2/home",
-	"S3":               "https://s3.console.aws.amazon.com/s3/home",
-	"Lambda":           "https://console.aws.amazon.com/lambda/home",
-	"CloudFormation":   "https://console.aws.amazon.com/cloudformation/home",
-	"CloudWatch":       "https://console.aws.amazon.com/cloudwatch/home",
+	"Console (Main)":  "https://signin.aws.amazon.com/console",
+	"SSO":             "https://d-{account_id}.awsapps.com/start", // Will be replaced with actual SSO URL
+	"EC2":             "https://console.aws.amazon.com/ec2/v2/home",
+	"S3":              "https://s3.console.aws.amazon.com/s3/home",
+	"Lambda":          "https://console.aws.amazon.com/lambda/home",
+	"CloudFormation":  "https://console.aws.amazon.com/cloudformation/home",
+	"CloudWatch":      "https://console.aws.amazon.com/cloudwatch/home",
 	"IAM":             "https://console.aws.amazon.com/iam/home",
 	"RDS":             "https://console.aws.amazon.com/rds/home",
 	"DynamoDB":        "https://console.aws.amazon.com/dynamodb/home",
 	"ECS":             "https://console.aws.amazon.com/ecs/home",
 	"EKS":             "https://console.aws.amazon.com/eks/home",
-	"API Gateway":      "https://console.aws.amazon.com/apigateway/home",
+	"API Gateway":     "https://console.aws.amazon.com/apigateway/home",
 	"Route 53":        "https://console.aws.amazon.com/route53/home",
 	"SQS":             "https://console.aws.amazon.com/sqs/home",
 	"SNS":             "https://console.aws.amazon.com/sns/home",
@@ -44,26 +41,20 @@ var openCmd = &cobra.Command{
 	Use:   "open",
 	Short: "Open AWS Console or specific services",
 	Long:  "🤖 Select and open AWS Console or services in your browser",
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: runResult(func(cmd *cobra.Command, args []string) (any, error) {
+		if err := validateGroupBy(); err != nil {
+			return nil, err
+		}
+
 		// Get available profiles
-		configPath := filepath.Join(os.Getenv("HOME"), ".aws", "config")
-		profiles := []string{"default"}
-
-		if cfg, err := ini.Load(configPath); err == nil {
-			for _, section := range cfg.Sections() {
-				name := section.Name()
-				if strings.HasPrefix(name, "profile ") {
-					profiles = append(profiles, strings.TrimPrefix(name, "profile "))
-				}
-			}
+		store, err := loadStore()
+		if err != nil {
+			return nil, err
 		}
 
 		// Get current profile
-		currentProfile := "default"
-		gsdProfilePath := filepath.Join(os.Getenv("HOME"), ".aws", ".gsd-current")
-		if data, err := os.ReadFile(gsdProfilePath); err == nil {
-			currentProfile = string(data)
-		}
+		currentProfile := activeProfile()
+		picker := newProfilePicker(store, store.Names(), currentProfile, "🤖 Select AWS profile:")
 
 		// Create questions
 		questions := []*survey.Question{
@@ -76,91 +67,61 @@ var openCmd = &cobra.Command{
 				},
 			},
 			{
-				Name: "profile",
-				Prompt: &survey.Select{
-					Message: "🤖 Select AWS profile:",
-					Options: profiles,
-					Default: currentProfile,
-				},
+				Name:     "profile",
+				Prompt:   picker.Select,
+				Validate: picker.validate,
 			},
 		}
 
-		// Custom styling
-		surveyOpts := survey.WithIcons(func(icons *survey.IconSet) {
-			icons.Question.Text = "🤖"
-			icons.Question.Format = "cyan"
-			icons.SelectFocus.Text = "→"
-			icons.SelectFocus.Format = "cyan"
-		})
-
 		// Get answers
 		answers := struct {
 			Service string
 			Profile string
 		}{}
 
-		err := survey.Ask(questions, &answers, surveyOpts)
-		if err != nil {
-			if err.Error() == "interrupt" {
-				fmt.Println("\n🤖 Operation cancelled")
-				os.Exit(0)
-			}
-			log.Fatalf("🤖 Error getting input: %v", err)
+		if err := ask(questions, &answers, robotIcons); err != nil {
+			return nil, err
 		}
 
 		// Get the URL for the selected service
 		url := awsServices[answers.Service]
 		if url == "" {
-			log.Fatalf("🤖 Service not found")
+			return nil, output.Errorf(output.CodeNotFound, "service '%s' not found", answers.Service)
 		}
 
-		// If SSO is selected, get the SSO URL from the profile configuration
+		// If SSO is selected, get the SSO URL from the profile configuration,
+		// following sso_session references to the shared session block
 		if answers.Service == "SSO" {
-			cfg, err := ini.Load(configPath)
-			if err != nil {
-				log.Fatalf("🤖 Unable to load AWS config: %v", err)
+			profile, ok := store.Get(answers.Profile)
+			if !ok || profile.SSOStartURL == "" {
+				return nil, output.Errorf(output.CodeNotFound, "no SSO configuration found for profile '%s'", answers.Profile)
 			}
-
-			sectionName := "profile " + answers.Profile
-			if answers.Profile == "default" {
-				sectionName = "default"
-			}
-
-			section := cfg.Section(sectionName)
-			
-			// First try to get the sso_session name
-			ssoSession := section.Key("sso_session").String()
-			var ssoStartURL string
-			
-			if ssoSession != "" {
-				// Look up the SSO session configuration
-				ssoSectionName := "sso-session " + ssoSession
-				if ssoSection := cfg.Section(ssoSectionName); ssoSection != nil {
-					ssoStartURL = ssoSection.Key("sso_start_url").String()
-				}
-			} else {
-				// Fallback to looking for sso_start_url directly in the profile (legacy configuration)
-				ssoStartURL = section.Key("sso_start_url").String()
-			}
-			
-			if ssoStartURL == "" {
-				log.Fatalf("🤖 No SSO configuration found for profile '%s'", answers.Profile)
-			}
-			
-			url = ssoStartURL
+			url = profile.SSOStartURL
 		}
 
 		// Open the URL in the default browser
-		err = openBrowser(url)
-		if err != nil {
-			log.Fatalf("🤖 Unable to open browser: %v", err)
+		if err := openBrowser(url); err != nil {
+			return nil, fmt.Errorf("unable to open browser: %w", err)
 		}
 
-		fmt.Printf("🤖 Opening %s for profile '%s'\n", answers.Service, answers.Profile)
-	},
+		return &openResult{Service: answers.Service, Profile: answers.Profile, URL: url}, nil
+	}),
+}
+
+// openResult is the result of `gsd open`.
+type openResult struct {
+	Service string `json:"service" yaml:"service"`
+	Profile string `json:"profile" yaml:"profile"`
+	URL     string `json:"url" yaml:"url"`
+}
+
+func (r *openResult) Text(w io.Writer) {
+	fmt.Fprintf(w, "🤖 Opening %s for profile '%s'\n", r.Service, r.Profile)
 }
 
 func init() {
+	openCmd.Flags().StringVar(&pickerGroupBy, "group-by", defaultGroupBy(), "Group the profile picker by session, account, tag or none; GSD_GROUP_BY sets the default")
+
 	rootCmd.AddCommand(openCmd)
 }
 
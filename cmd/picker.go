@@ -0,0 +1,306 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/AlecAivazis/survey/v2/core"
+	"github.com/aphexlog/gsd/internal/output"
+	"github.com/aphexlog/gsd/internal/profiles"
+	"github.com/aphexlog/gsd/internal/ssocache"
+	"github.com/aphexlog/gsd/internal/state"
+)
+
+// Ways to group profiles in a picker.
+const (
+	groupBySession = "session"
+	groupByAccount = "account"
+	groupByTag     = "tag"
+	groupByNone    = "none"
+)
+
+var groupByValues = []string{groupBySession, groupByAccount, groupByTag, groupByNone}
+
+// pickerGroupBy is bound to --group-by on the commands with a profile
+// picker. GSD_GROUP_BY sets the default.
+var pickerGroupBy string
+
+// pickerRecent is how many frequently used profiles the picker lists first.
+const pickerRecent = 5
+
+func defaultGroupBy() string {
+	if v := os.Getenv("GSD_GROUP_BY"); v != "" {
+		return v
+	}
+	return groupBySession
+}
+
+func validateGroupBy() error {
+	for _, v := range groupByValues {
+		if pickerGroupBy == v {
+			return nil
+		}
+	}
+	return output.Errorf(output.CodeInvalidArgument, "--group-by must be one of %s, not '%s'",
+		strings.Join(groupByValues, ", "), pickerGroupBy)
+}
+
+// profilePicker is a profile Select that lists the most used profiles
+// first and groups the rest under headings. Headings are options too, as
+// survey has nothing else, so validate rejects picking one.
+type profilePicker struct {
+	*survey.Select
+	headings map[int]bool
+}
+
+// pickerOption is one line of a profilePicker.
+type pickerOption struct {
+	label   string
+	heading bool
+	info    *profileInfo
+	// members are the indexes of a heading's profiles.
+	members []int
+}
+
+func newProfilePicker(store *profiles.Store, names []string, current, message string) *profilePicker {
+	infos := profileInfos(store)
+	var options []pickerOption
+	addGroup := func(heading string, group []string) {
+		if len(group) == 0 {
+			return
+		}
+		h := len(options)
+		options = append(options, pickerOption{label: "── " + heading + " ──", heading: true})
+		for _, name := range group {
+			options[h].members = append(options[h].members, len(options))
+			options = append(options, pickerOption{label: name, info: infos[name]})
+		}
+	}
+
+	wanted := make(map[string]bool, len(names))
+	for _, name := range names {
+		wanted[name] = true
+	}
+	var recent []string
+	for _, name := range state.FrequentProfiles(state.MaxSwitches) {
+		if wanted[name] && len(recent) < pickerRecent {
+			recent = append(recent, name)
+			delete(wanted, name)
+		}
+	}
+
+	var rest []string
+	for _, name := range names {
+		if wanted[name] {
+			rest = append(rest, name)
+		}
+	}
+
+	// A short list reads fine without headings.
+	if len(recent) == 0 && (pickerGroupBy == groupByNone || len(names) <= pickerRecent) {
+		for _, name := range rest {
+			options = append(options, pickerOption{label: name, info: infos[name]})
+		}
+	} else {
+		addGroup("Recent", recent)
+		if pickerGroupBy == groupByNone {
+			addGroup("All profiles", rest)
+		} else {
+			groups := make(map[string][]string)
+			for _, name := range rest {
+				key := infos[name].group(pickerGroupBy)
+				groups[key] = append(groups[key], name)
+			}
+			for _, key := range sortedGroups(groups) {
+				addGroup(key, groups[key])
+			}
+		}
+	}
+
+	// Start on a profile rather than a heading when none is current.
+	def := defaultOption(names, current)
+	if def == nil && len(options) > 1 && options[0].heading {
+		def = options[1].label
+	}
+
+	labels := make([]string, len(options))
+	headings := make(map[int]bool)
+	for i, o := range options {
+		labels[i] = o.label
+		if o.heading {
+			headings[i] = true
+		}
+	}
+
+	return &profilePicker{
+		headings: headings,
+		Select: &survey.Select{
+			Message:  message,
+			Options:  labels,
+			Default:  def,
+			PageSize: 15,
+			Help:     "Type to filter by name, alias, account ID, region, tag or credential status",
+			Description: func(value string, index int) string {
+				if options[index].heading {
+					return ""
+				}
+				return options[index].info.describe()
+			},
+			Filter: func(filter, value string, index int) bool {
+				filter = strings.ToLower(filter)
+				o := options[index]
+				if !o.heading {
+					return o.info.matches(filter)
+				}
+				for _, m := range o.members {
+					if options[m].info.matches(filter) {
+						return true
+					}
+				}
+				return false
+			},
+		},
+	}
+}
+
+// sortedGroups orders group headings by name, with the catch-all groups
+// ("No account", "Untagged") last.
+func sortedGroups(groups map[string][]string) []string {
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		ci, cj := catchAllGroups[keys[i]], catchAllGroups[keys[j]]
+		if ci != cj {
+			return cj
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+var catchAllGroups = map[string]bool{"No account": true, "Untagged": true}
+
+func (p *profilePicker) validate(ans interface{}) error {
+	if a, ok := ans.(core.OptionAnswer); ok && p.headings[a.Index] {
+		return fmt.Errorf("pick a profile, not a group heading")
+	}
+	return nil
+}
+
+// askProfile asks for one of names with a profilePicker.
+func askProfile(store *profiles.Store, names []string, current, message string) (string, error) {
+	picker := newProfilePicker(store, names, current, message)
+	var selected string
+	if err := askOne(picker.Select, &selected, robotIcons, survey.WithValidator(picker.validate)); err != nil {
+		return "", err
+	}
+	return selected, nil
+}
+
+// profileInfo is what pickers show about a profile.
+type profileInfo struct {
+	profile *profiles.Profile
+	session string
+	account string
+	status  string
+}
+
+// profileInfos collects the picker details of every profile, reading each
+// cached SSO token once.
+func profileInfos(store *profiles.Store) map[string]*profileInfo {
+	statuses := make(map[string]string)
+	tokenStatus := func(p *profiles.Profile) string {
+		key := p.SSOSession
+		if key == "" {
+			key = p.SSOStartURL
+		}
+		if _, ok := statuses[key]; !ok {
+			statuses[key] = ssocache.Status(ssocache.LoadForProfile(p))
+		}
+		return statuses[key]
+	}
+
+	infos := make(map[string]*profileInfo)
+	for _, p := range store.Profiles() {
+		info := &profileInfo{profile: p, account: p.AccountID()}
+		infos[p.Name] = info
+
+		// Role profiles get their credentials, and so their status and
+		// session, from the end of the source_profile chain.
+		chain, err := store.RoleChain(p.Name)
+		if err != nil {
+			info.status = "broken source_profile"
+			continue
+		}
+		root, _ := store.Get(chain[len(chain)-1])
+		switch root.Type {
+		case profiles.TypeSSO:
+			info.session = root.SSOSession
+			if info.session == "" {
+				info.session = root.SSOStartURL
+			}
+			info.status = tokenStatus(root)
+		case profiles.TypeAccessKeys:
+			info.status = "static keys"
+		case profiles.TypeCredentialProcess:
+			info.status = "credential process"
+		}
+		if root.Name != p.Name && info.status != "" {
+			info.status += " via " + root.Name
+		}
+	}
+	return infos
+}
+
+// describe renders the type, account ID, region and credential status.
+func (i *profileInfo) describe() string {
+	parts := []string{i.profile.Type.Label()}
+	for _, s := range []string{i.account, i.profile.Region, i.status} {
+		if s != "" {
+			parts = append(parts, s)
+		}
+	}
+	return strings.Join(parts, " · ")
+}
+
+// matches reports whether a lowercase filter appears in any detail shown
+// for the profile, or in its aliases and tags.
+func (i *profileInfo) matches(filter string) bool {
+	if filter == "" {
+		return true
+	}
+	fields := append([]string{i.profile.Name, i.account, i.profile.Region, i.status, i.session}, i.profile.Aliases...)
+	fields = append(fields, i.profile.Tags...)
+	for _, f := range fields {
+		if strings.Contains(strings.ToLower(f), filter) {
+			return true
+		}
+	}
+	return false
+}
+
+// group returns the heading the profile is listed under.
+func (i *profileInfo) group(by string) string {
+	switch by {
+	case groupByAccount:
+		if i.account == "" {
+			return "No account"
+		}
+		return "Account " + i.account
+	case groupByTag:
+		if len(i.profile.Tags) == 0 {
+			return "Untagged"
+		}
+		return "Tag " + i.profile.Tags[0]
+	default:
+		if i.session != "" {
+			return "SSO " + i.session
+		}
+		return i.profile.Type.Label()
+	}
+}
@@ -0,0 +1,337 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/aphexlog/gsd/internal/output"
+	"github.com/aphexlog/gsd/internal/pin"
+	"github.com/aphexlog/gsd/internal/profiles"
+	"github.com/aphexlog/gsd/internal/shell"
+	"github.com/aphexlog/gsd/internal/state"
+	"github.com/spf13/cobra"
+)
+
+var pinRegion string
+
+var pinCmd = &cobra.Command{
+	Use:   "pin <profile>",
+	Short: "Use a profile automatically in this directory",
+	Long: `Write a .gsd.yaml in the current directory naming a profile (and
+optionally a region). With the shell integration from 'gsd shell-init', the
+profile becomes active whenever you cd into the directory or below it, and
+the previous one comes back when you leave.
+
+A .gsd-profile file containing just a profile name works too. Pin files
+written by someone else, or changed since, only take effect once you run
+'gsd allow'.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runResult(func(cmd *cobra.Command, args []string) (any, error) {
+		name := args[0]
+		if pinRegion != "" {
+			if err := validateRegion(pinRegion); err != nil {
+				return nil, output.Errorf(output.CodeInvalidArgument, "--region: %w", err)
+			}
+		}
+		store, err := loadStore()
+		if err != nil {
+			return nil, err
+		}
+		if !store.Has(name) {
+			return nil, output.Errorf(output.CodeNotFound, "profile '%s' not found", name)
+		}
+
+		dir, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+		f, err := pin.Write(dir, name, pinRegion)
+		if err != nil {
+			return nil, fmt.Errorf("unable to write pin file: %w", err)
+		}
+		if err := state.Trust(f.Path, f.Data); err != nil {
+			return nil, fmt.Errorf("unable to trust %s: %w", f.Path, err)
+		}
+		return activatePin(store, "pinned", f)
+	}),
+}
+
+var allowCmd = &cobra.Command{
+	Use:   "allow [path]",
+	Short: "Trust the pin file for this directory",
+	Long: `Trust the nearest .gsd.yaml or .gsd-profile, or the one at path, so the
+shell integration activates its profile. Trust is tied to the file's
+content: after it changes, it has to be allowed again.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runResult(func(cmd *cobra.Command, args []string) (any, error) {
+		f, err := findPin(args)
+		if err != nil {
+			return nil, err
+		}
+		store, err := loadStore()
+		if err != nil {
+			return nil, err
+		}
+		if !store.Has(f.Profile) {
+			return nil, output.Errorf(output.CodeNotFound, "%s names profile '%s', which does not exist", f.Path, f.Profile)
+		}
+		if err := state.Trust(f.Path, f.Data); err != nil {
+			return nil, fmt.Errorf("unable to trust %s: %w", f.Path, err)
+		}
+		return activatePin(store, "allowed", f)
+	}),
+}
+
+var denyCmd = &cobra.Command{
+	Use:   "deny [path]",
+	Short: "Stop trusting the pin file for this directory",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: runResult(func(cmd *cobra.Command, args []string) (any, error) {
+		f, err := findPin(args)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := state.Untrust(f.Path); err != nil {
+			return nil, fmt.Errorf("unable to update trusted files: %w", err)
+		}
+		if shell.Active() && os.Getenv(shell.PinVar) == f.Path {
+			if _, err := leavePin(); err != nil {
+				return nil, err
+			}
+		}
+		return &pinResult{Action: "denied", File: f.Path, Profile: f.Profile}, nil
+	}),
+}
+
+var hookCmd = &cobra.Command{
+	Use:    "hook",
+	Short:  "Activate the profile pinned for the current directory",
+	Long:   `Run by the shell integration whenever the working directory changes.`,
+	Hidden: true,
+	Args:   cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !shell.Active() {
+			return output.Errorf(output.CodeInvalidArgument, "gsd hook is run by the shell integration; see 'gsd shell-init --help'")
+		}
+		dir, err := os.Getwd()
+		if err != nil {
+			return nil
+		}
+
+		f, err := pin.Find(dir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  gsd: %v\n", err)
+			f = nil
+		}
+		// Nothing to do if this file is active already, unless it was
+		// edited since and so has to be trusted again.
+		if f != nil && os.Getenv(shell.PinVar) == f.Path && os.Getenv(shell.PinHashVar) == state.ContentHash(f.Data) {
+			return nil
+		}
+		if f != nil && !state.Trusted(f.Path, f.Data) {
+			fmt.Fprintf(os.Stderr, "⚠️  gsd: %s wants profile '%s'; run 'gsd allow' to trust it\n", f.Path, f.Profile)
+			f = nil
+		}
+
+		var store *profiles.Store
+		if f != nil {
+			if store, err = loadStore(); err != nil {
+				fmt.Fprintf(os.Stderr, "⚠️  gsd: %v\n", err)
+				return nil
+			}
+			if !store.Has(f.Profile) {
+				fmt.Fprintf(os.Stderr, "⚠️  gsd: %s names profile '%s', which does not exist\n", f.Path, f.Profile)
+				f = nil
+			}
+		}
+
+		if f == nil {
+			restored, err := leavePin()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "⚠️  gsd: %v\n", err)
+			} else if restored != nil {
+				if profile := restored["AWS_PROFILE"]; profile != "" {
+					fmt.Fprintf(os.Stderr, "🤖 gsd: back to profile '%s'\n", profile)
+				} else {
+					fmt.Fprintln(os.Stderr, "🤖 gsd: AWS_PROFILE unset")
+				}
+			}
+			return nil
+		}
+
+		if _, err := enterPin(store, f); err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  gsd: %v\n", err)
+			return nil
+		}
+		fmt.Fprintf(os.Stderr, "🤖 gsd: using profile '%s' from %s\n", f.Profile, f.Path)
+		return nil
+	},
+}
+
+// findPin returns the pin file at path (a file or a directory), or the one
+// nearest the working directory.
+func findPin(args []string) (*pin.File, error) {
+	dir := "."
+	if len(args) == 1 {
+		if info, err := os.Stat(args[0]); err == nil && !info.IsDir() {
+			path, err := filepath.Abs(args[0])
+			if err != nil {
+				return nil, err
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			f, err := pin.Parse(path, data)
+			if err != nil {
+				return nil, output.Errorf(output.CodeInvalidArgument, "%w", err)
+			}
+			return f, nil
+		}
+		dir = args[0]
+	}
+
+	f, err := pin.Find(dir)
+	if err != nil {
+		return nil, output.Errorf(output.CodeInvalidArgument, "%w", err)
+	}
+	if f == nil {
+		return nil, output.Errorf(output.CodeNotFound, "no %s or %s found in %s or its parents", pin.YAMLFile, pin.ProfileFile, dir)
+	}
+	return f, nil
+}
+
+// activatePin makes a freshly written or allowed pin file take effect in
+// the calling shell if gsd runs through the shell integration.
+func activatePin(store *profiles.Store, action string, f *pin.File) (*pinResult, error) {
+	result := &pinResult{Action: action, File: f.Path, Profile: f.Profile, Region: f.Region}
+	if !shell.Active() {
+		return result, nil
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		return result, nil
+	}
+	// Only if the file is the one that applies here, not one for some
+	// other directory passed by path.
+	if nearest, err := pin.Find(dir); err != nil || nearest == nil || nearest.Path != f.Path {
+		return result, nil
+	}
+	if _, err := enterPin(store, f); err != nil {
+		return nil, err
+	}
+	result.Active = true
+	return result, nil
+}
+
+// enterPin exports the pinned profile, remembering what to put back when
+// the directory is left. Moving between pinned directories keeps the
+// values from before the first one.
+func enterPin(store *profiles.Store, f *pin.File) (map[string]string, error) {
+	restore := pinRestore()
+	if restore == nil {
+		restore = make(map[string]string)
+	}
+
+	vars := map[string]string{"AWS_PROFILE": f.Profile}
+	switch {
+	case f.Region != "":
+		vars["AWS_REGION"] = f.Region
+	case shell.ExportRegion():
+		p, _ := store.Get(f.Profile)
+		vars["AWS_REGION"] = p.Region
+	default:
+		// A pin left behind may have set the region; this one doesn't.
+		if region, ok := restore["AWS_REGION"]; ok {
+			vars["AWS_REGION"] = region
+		}
+	}
+	for key := range vars {
+		if _, ok := restore[key]; !ok {
+			restore[key] = os.Getenv(key)
+		}
+	}
+	data, err := json.Marshal(restore)
+	if err != nil {
+		return nil, err
+	}
+
+	vars[shell.PinVar] = f.Path
+	vars[shell.PinHashVar] = state.ContentHash(f.Data)
+	vars[shell.RestoreVar] = string(data)
+	if err := shell.Export(vars); err != nil {
+		return nil, fmt.Errorf("unable to update the shell environment: %w", err)
+	}
+	return vars, nil
+}
+
+// leavePin puts back the variables from before a pinned directory was
+// entered and returns them, or nil if no pin was active.
+func leavePin() (map[string]string, error) {
+	if os.Getenv(shell.PinVar) == "" {
+		return nil, nil
+	}
+	restore := pinRestore()
+	vars := make(map[string]string)
+	for key, value := range restore {
+		vars[key] = value
+	}
+	vars[shell.PinVar] = ""
+	vars[shell.PinHashVar] = ""
+	vars[shell.RestoreVar] = ""
+	if err := shell.Export(vars); err != nil {
+		return nil, fmt.Errorf("unable to update the shell environment: %w", err)
+	}
+	if restore == nil {
+		restore = make(map[string]string)
+	}
+	return restore, nil
+}
+
+// pinRestore reads the values saved by enterPin. An empty value means the
+// variable was unset.
+func pinRestore() map[string]string {
+	var restore map[string]string
+	if data := os.Getenv(shell.RestoreVar); data != "" {
+		_ = json.Unmarshal([]byte(data), &restore)
+	}
+	return restore
+}
+
+// pinResult is the result of pin, allow and deny.
+type pinResult struct {
+	Action  string `json:"action" yaml:"action"`
+	File    string `json:"file" yaml:"file"`
+	Profile string `json:"profile" yaml:"profile"`
+	Region  string `json:"region,omitempty" yaml:"region,omitempty"`
+	Active  bool   `json:"active" yaml:"active"`
+}
+
+func (r *pinResult) Text(w io.Writer) {
+	switch r.Action {
+	case "pinned":
+		fmt.Fprintf(w, "📌 Pinned profile '%s' in %s\n", r.Profile, r.File)
+	case "allowed":
+		fmt.Fprintf(w, "✅ Allowed %s (profile '%s')\n", r.File, r.Profile)
+	default:
+		fmt.Fprintf(w, "🚫 %s is no longer trusted\n", r.File)
+		return
+	}
+	if r.Active {
+		fmt.Fprintf(w, "🤖 Using profile '%s' in this shell\n", r.Profile)
+	} else if !shell.Active() {
+		fmt.Fprintln(w, "ℹ️  Set up 'gsd shell-init' to activate it automatically")
+	}
+}
+
+func init() {
+	pinCmd.Flags().StringVar(&pinRegion, "region", "", "Also set AWS_REGION in this directory")
+
+	rootCmd.AddCommand(pinCmd)
+	rootCmd.AddCommand(allowCmd)
+	rootCmd.AddCommand(denyCmd)
+	rootCmd.AddCommand(hookCmd)
+}
@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aphexlog/gsd/internal/pin"
+	"github.com/aphexlog/gsd/internal/shell"
+	"github.com/aphexlog/gsd/internal/state"
+)
+
+// fakeShell makes the shell integration look active, with exports going to
+// a temporary file.
+func fakeShell(t *testing.T) {
+	t.Helper()
+	envFile := filepath.Join(t.TempDir(), "env")
+	if err := os.WriteFile(envFile, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(shell.EnvFileVar, envFile)
+	t.Setenv(shell.ShellVar, "bash")
+	t.Setenv(shell.ExportRegionVar, "")
+	t.Setenv(shell.PinVar, "")
+	t.Setenv(shell.PinHashVar, "")
+	t.Setenv(shell.RestoreVar, "")
+}
+
+// applyExports does what the wrapper does with the variables gsd exported.
+func applyExports(t *testing.T, vars map[string]string) {
+	t.Helper()
+	for key, value := range vars {
+		t.Setenv(key, value)
+	}
+}
+
+func TestPinTransitions(t *testing.T) {
+	store := loadTestStore(t, "[profile dev]\nregion = us-east-1\n\n[profile prod]\nregion = eu-central-1\n")
+	withRegion := &pin.File{Path: "/a/.gsd.yaml", Profile: "dev", Region: "eu-west-1", Data: []byte("a")}
+	without := &pin.File{Path: "/b/.gsd.yaml", Profile: "prod", Data: []byte("b")}
+
+	cases := []struct {
+		name   string
+		path   []*pin.File
+		region string
+	}{
+		{"region pin", []*pin.File{withRegion}, "eu-west-1"},
+		{"region pin then plain pin", []*pin.File{withRegion, without}, "us-west-2"},
+		{"plain pin then region pin", []*pin.File{without, withRegion}, "eu-west-1"},
+		{"plain pin", []*pin.File{without}, "us-west-2"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			fakeShell(t)
+			t.Setenv("AWS_PROFILE", "personal")
+			t.Setenv("AWS_REGION", "us-west-2")
+
+			for _, f := range tc.path {
+				vars, err := enterPin(store, f)
+				if err != nil {
+					t.Fatal(err)
+				}
+				applyExports(t, vars)
+			}
+			last := tc.path[len(tc.path)-1]
+			if got := os.Getenv("AWS_PROFILE"); got != last.Profile {
+				t.Errorf("AWS_PROFILE = %q, want %q", got, last.Profile)
+			}
+			if got := os.Getenv("AWS_REGION"); got != tc.region {
+				t.Errorf("AWS_REGION = %q, want %q", got, tc.region)
+			}
+
+			vars, err := leavePin()
+			if err != nil {
+				t.Fatal(err)
+			}
+			applyExports(t, vars)
+			if got := os.Getenv("AWS_PROFILE"); got != "personal" {
+				t.Errorf("after leaving AWS_PROFILE = %q, want personal", got)
+			}
+			if got := os.Getenv("AWS_REGION"); got != "us-west-2" {
+				t.Errorf("after leaving AWS_REGION = %q, want us-west-2", got)
+			}
+		})
+	}
+}
+
+// runHook runs `gsd hook` in dir and applies what it exported, which it
+// returns.
+func runHook(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	envFile := os.Getenv(shell.EnvFileVar)
+	if err := os.WriteFile(envFile, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(dir)
+	if err := hookCmd.RunE(hookCmd, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(envFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Test values never hold quotes, so the bash syntax is easy to undo.
+	vars := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if key, ok := strings.CutPrefix(line, "unset "); ok {
+			vars[key] = ""
+		} else if key, value, ok := strings.Cut(strings.TrimPrefix(line, "export "), "="); ok {
+			vars[key] = strings.Trim(value, "'")
+		}
+	}
+	applyExports(t, vars)
+	return vars
+}
+
+func TestHook(t *testing.T) {
+	useTestFiles(t, "[profile dev]\nregion = us-east-1\n\n[profile prod]\nregion = eu-central-1\n")
+	root := t.TempDir()
+	project := filepath.Join(root, "project")
+	if err := os.MkdirAll(filepath.Join(project, "src"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	writePin := func(profile string) *pin.File {
+		t.Helper()
+		f, err := pin.Write(project, profile, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return f
+	}
+
+	fakeShell(t)
+	t.Setenv("AWS_PROFILE", "personal")
+	t.Setenv("AWS_REGION", "")
+	f := writePin("dev")
+
+	// An untrusted pin does nothing.
+	if vars := runHook(t, project); len(vars) != 0 {
+		t.Errorf("untrusted pin exported %v", vars)
+	}
+
+	// A trusted one applies in the directory and below, once.
+	if err := state.Trust(f.Path, f.Data); err != nil {
+		t.Fatal(err)
+	}
+	runHook(t, project)
+	if got := os.Getenv("AWS_PROFILE"); got != "dev" {
+		t.Fatalf("AWS_PROFILE = %q, want dev", got)
+	}
+	if vars := runHook(t, filepath.Join(project, "src")); len(vars) != 0 {
+		t.Errorf("active pin exported again: %v", vars)
+	}
+
+	// Editing it withdraws the trust, even while it is active, and puts
+	// back the profile from before.
+	writePin("prod")
+	runHook(t, filepath.Join(project, "src"))
+	if got := os.Getenv("AWS_PROFILE"); got != "personal" {
+		t.Errorf("after edit AWS_PROFILE = %q, want personal", got)
+	}
+	if got := os.Getenv(shell.PinVar); got != "" {
+		t.Errorf("edited pin still active: %s", got)
+	}
+
+	// Trusting the new content applies it; leaving restores the values
+	// from before the pin.
+	f = writePin("prod")
+	if err := state.Trust(f.Path, f.Data); err != nil {
+		t.Fatal(err)
+	}
+	runHook(t, project)
+	if got := os.Getenv("AWS_PROFILE"); got != "prod" {
+		t.Errorf("AWS_PROFILE = %q, want prod", got)
+	}
+	vars := runHook(t, root)
+	if vars["AWS_PROFILE"] != "personal" || vars[shell.PinVar] != "" || vars[shell.RestoreVar] != "" {
+		t.Errorf("leaving exported %v", vars)
+	}
+}
@@ -0,0 +1,307 @@
+package cmd
+
+import (
+	"bufio"
+	"io"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/aphexlog/gsd/internal/output"
+	"github.com/aphexlog/gsd/internal/profiles"
+	"github.com/spf13/cobra"
+)
+
+// Authentication methods offered by config add.
+const (
+	authSSO        = "AWS SSO"
+	authAccessKeys = "Access Keys"
+	authAssumeRole = "Assume Role"
+)
+
+// profileFlags are the settings config add and config edit accept on the
+// command line. Anything left empty is prompted for when there is a terminal.
+type profileFlags struct {
+	Name          string
+	Region        string
+	SSOSession    string
+	SSOStartURL   string
+	SSORegion     string
+	SSOAccountID  string
+	SSORoleName   string
+	RoleARN       string
+	SourceProfile string
+	AccessKeyID   string
+	SecretStdin   bool
+}
+
+func (f *profileFlags) register(cmd *cobra.Command, nameUsage string) {
+	flags := cmd.Flags()
+	flags.StringVar(&f.Name, "name", "", nameUsage)
+	flags.StringVar(&f.Region, "region", "", "AWS region")
+	flags.StringVar(&f.SSOSession, "sso-session", "", "SSO session to use; created if --sso-start-url is also given")
+	flags.StringVar(&f.SSOStartURL, "sso-start-url", "", "SSO start URL")
+	flags.StringVar(&f.SSORegion, "sso-region", "", "SSO region (defaults to --region)")
+	flags.StringVar(&f.SSOAccountID, "sso-account-id", "", "12-digit AWS account ID for SSO")
+	flags.StringVar(&f.SSORoleName, "sso-role-name", "", "SSO permission set (role) name")
+	flags.StringVar(&f.RoleARN, "role-arn", "", "ARN of the role to assume")
+	flags.StringVar(&f.SourceProfile, "source-profile", "", "Profile whose credentials assume the role")
+	flags.StringVar(&f.AccessKeyID, "access-key-id", "", "AWS access key ID")
+	flags.BoolVar(&f.SecretStdin, "secret-access-key-stdin", false, "Read the AWS secret access key from stdin")
+}
+
+func (f *profileFlags) hasSSO() bool {
+	return f.SSOSession != "" || f.SSOStartURL != "" || f.SSORegion != "" || f.SSOAccountID != "" || f.SSORoleName != ""
+}
+
+func (f *profileFlags) hasRole() bool {
+	return f.RoleARN != "" || f.SourceProfile != ""
+}
+
+func (f *profileFlags) hasAccessKeys() bool {
+	return f.AccessKeyID != "" || f.SecretStdin
+}
+
+// authType infers the authentication method from the flags that were set,
+// or returns "" if none were.
+func (f *profileFlags) authType() (string, error) {
+	var types []string
+	if f.hasSSO() {
+		types = append(types, authSSO)
+	}
+	if f.hasRole() {
+		types = append(types, authAssumeRole)
+	}
+	if f.hasAccessKeys() {
+		types = append(types, authAccessKeys)
+	}
+	switch len(types) {
+	case 0:
+		return "", nil
+	case 1:
+		return types[0], nil
+	default:
+		return "", output.Errorf(output.CodeInvalidArgument, "flags for %s can't be combined", strings.Join(types, " and "))
+	}
+}
+
+// validate checks the flags that have a fixed format.
+func (f *profileFlags) validate() error {
+	if f.Region != "" {
+		if err := validateRegion(f.Region); err != nil {
+			return output.Errorf(output.CodeInvalidArgument, "--region: %w", err)
+		}
+	}
+	if f.SSORegion != "" {
+		if err := validateRegion(f.SSORegion); err != nil {
+			return output.Errorf(output.CodeInvalidArgument, "--sso-region: %w", err)
+		}
+	}
+	if f.SSOAccountID != "" {
+		if err := validateAccountID(f.SSOAccountID); err != nil {
+			return output.Errorf(output.CodeInvalidArgument, "--sso-account-id: %w", err)
+		}
+	}
+	if f.RoleARN != "" {
+		if err := validateRoleARN(f.RoleARN); err != nil {
+			return output.Errorf(output.CodeInvalidArgument, "--role-arn: %w", err)
+		}
+	}
+	return nil
+}
+
+// ssoSession resolves the SSO session named by the flags, reporting whether
+// it still has to be written. It returns nil if no session flags were given.
+// An unknown --sso-session is created from --sso-start-url, and a bare
+// --sso-start-url picks the existing session with that URL.
+func (f *profileFlags) ssoSession(store *profiles.Store, region string) (*profiles.SSOSession, bool, error) {
+	if f.SSOSession == "" && f.SSOStartURL == "" {
+		return nil, false, nil
+	}
+
+	if f.SSOSession == "" {
+		for _, sess := range store.SSOSessions() {
+			if sess.StartURL == f.SSOStartURL {
+				return sess, false, nil
+			}
+		}
+		return nil, false, output.Errorf(output.CodeInvalidArgument, "no SSO session uses %s; pass --sso-session to create one", f.SSOStartURL)
+	}
+
+	if sess, ok := store.GetSSOSession(f.SSOSession); ok {
+		if f.SSOStartURL != "" && f.SSOStartURL != sess.StartURL {
+			return nil, false, output.Errorf(output.CodeConflict, "SSO session '%s' uses %s; change it with 'gsd sso edit'", sess.Name, sess.StartURL)
+		}
+		return sess, false, nil
+	}
+	if f.SSOStartURL == "" {
+		return nil, false, output.Errorf(output.CodeNotFound, "SSO session '%s' does not exist; pass --sso-start-url to create it", f.SSOSession)
+	}
+
+	ssoRegion := f.SSORegion
+	if ssoRegion == "" {
+		ssoRegion = region
+	}
+	return &profiles.SSOSession{
+		Name:               f.SSOSession,
+		StartURL:           f.SSOStartURL,
+		Region:             ssoRegion,
+		RegistrationScopes: profiles.DefaultRegistrationScopes,
+	}, true, nil
+}
+
+// roleSettings builds source_profile role settings from the flags, prompting
+// for whichever of the role ARN and source profile is missing.
+func (f *profileFlags) roleSettings(store *profiles.Store, p *profiles.Profile) (map[string]string, error) {
+	roleARN := f.RoleARN
+	if roleARN == "" {
+		if err := requireTerminal("--role-arn"); err != nil {
+			return nil, err
+		}
+		var err error
+		if roleARN, err = askRoleARN(p); err != nil {
+			return nil, err
+		}
+	}
+
+	source := f.SourceProfile
+	if source == "" {
+		var sources []string
+		for _, name := range store.Names() {
+			if name != p.Name {
+				sources = append(sources, name)
+			}
+		}
+		if len(sources) == 0 {
+			return nil, output.Errorf(output.CodeNotFound, "no other profiles exist to use as the source profile")
+		}
+		err := askFlag("--source-profile", &survey.Select{
+			Message: "Source profile:",
+			Options: sources,
+			Default: defaultOption(sources, p.SourceProfile),
+			Help:    "Profile whose credentials are used to assume the role",
+		}, &source)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return map[string]string{
+		"role_arn":                roleARN,
+		"source_profile":          source,
+		"credential_source":       "",
+		"web_identity_token_file": "",
+	}, nil
+}
+
+// secretAccessKey reads the secret from stdin when --secret-access-key-stdin
+// is set and prompts for it otherwise.
+func (f *profileFlags) secretAccessKey(stdin io.Reader, message string) (string, error) {
+	if f.SecretStdin {
+		return readSecret(stdin)
+	}
+	var secret string
+	err := askFlag("--secret-access-key-stdin", &survey.Password{Message: message}, &secret, survey.WithValidator(survey.Required))
+	return secret, err
+}
+
+// readSecret returns the first line of r, so both `echo $SECRET |` and a
+// here-string work.
+func readSecret(r io.Reader) (string, error) {
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	secret := strings.TrimSpace(line)
+	if secret == "" {
+		return "", output.Errorf(output.CodeInvalidArgument, "no secret access key on stdin")
+	}
+	return secret, nil
+}
+
+// hasSettings reports whether any flag other than --name was given.
+func (f *profileFlags) hasSettings() bool {
+	return f.Region != "" || f.hasSSO() || f.hasRole() || f.hasAccessKeys()
+}
+
+// edit turns the flags into changes to an existing profile. Input is read
+// up front so the returned func can run under the store lock.
+func (f *profileFlags) edit(stdin io.Reader, store *profiles.Store, p *profiles.Profile) (func(*profiles.Store) error, error) {
+	region := p.SSORegion
+	if region == "" {
+		region = p.Region
+	}
+
+	var session *profiles.SSOSession
+	var newSession bool
+	if f.SSOSession != "" {
+		var err error
+		if session, newSession, err = f.ssoSession(store, region); err != nil {
+			return nil, err
+		}
+	} else if (f.SSOStartURL != "" || f.SSORegion != "") && p.SSOSession != "" {
+		return nil, output.Errorf(output.CodeInvalidArgument, "profile '%s' uses SSO session '%s'; change its settings with 'gsd sso edit'", p.Name, p.SSOSession)
+	}
+
+	var roleSettings map[string]string
+	if f.hasRole() {
+		if f.RoleARN == "" && p.RoleARN == "" {
+			return nil, output.Errorf(output.CodeInvalidArgument, "profile '%s' has no role_arn; pass --role-arn", p.Name)
+		}
+		roleSettings = make(map[string]string)
+		if f.RoleARN != "" {
+			roleSettings["role_arn"] = f.RoleARN
+		}
+		if f.SourceProfile != "" {
+			roleSettings["source_profile"] = f.SourceProfile
+			roleSettings["credential_source"] = ""
+			roleSettings["web_identity_token_file"] = ""
+		}
+	}
+
+	var secret string
+	if f.SecretStdin {
+		var err error
+		if secret, err = readSecret(stdin); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(store *profiles.Store) error {
+		set := func(key, value string) {
+			if value != "" {
+				store.SetConfig(p.Name, key, value)
+			}
+		}
+		set("region", f.Region)
+
+		if session != nil {
+			if newSession {
+				store.PutSSOSession(session)
+			}
+			// Session settings live in the shared block, not the profile
+			store.DeleteConfigKey(p.Name, "sso_start_url")
+			store.DeleteConfigKey(p.Name, "sso_region")
+			store.SetConfig(p.Name, "sso_session", session.Name)
+		} else {
+			set("sso_start_url", f.SSOStartURL)
+			set("sso_region", f.SSORegion)
+		}
+		set("sso_account_id", f.SSOAccountID)
+		set("sso_role_name", f.SSORoleName)
+
+		if roleSettings != nil {
+			applySettings(store, p.Name, roleSettings)
+			if _, err := store.RoleChain(p.Name); err != nil {
+				return err
+			}
+		}
+
+		if f.AccessKeyID != "" {
+			store.SetCredential(p.Name, "aws_access_key_id", f.AccessKeyID)
+		}
+		if secret != "" {
+			store.SetCredential(p.Name, "aws_secret_access_key", secret)
+		}
+		return nil
+	}, nil
+}
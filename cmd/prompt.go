@@ -0,0 +1,250 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/aphexlog/gsd/internal/output"
+	"github.com/aphexlog/gsd/internal/profiles"
+	"github.com/aphexlog/gsd/internal/ssocache"
+	"github.com/aphexlog/gsd/internal/state"
+	"github.com/spf13/cobra"
+)
+
+const defaultPromptFormat = `{{.Profile}}{{with .Region}}@{{.}}{{end}}{{with .Expiry}} {{.}}{{end}}`
+
+// defaultPromptColors makes production profiles stand out unless
+// GSD_PROMPT_COLORS or --colors say otherwise.
+const defaultPromptColors = "prod=red,production=red,staging=yellow"
+
+// promptStyles are the ways of marking up color codes, one per consumer.
+var promptStyles = []string{"ansi", "bash", "zsh", "tmux", "none"}
+
+var ansiColors = map[string]string{
+	"black":   "30",
+	"red":     "31",
+	"green":   "32",
+	"yellow":  "33",
+	"blue":    "34",
+	"magenta": "35",
+	"cyan":    "36",
+	"white":   "37",
+}
+
+var (
+	promptFormat string
+	promptColors string
+	promptStyle  string
+)
+
+var promptCmd = &cobra.Command{
+	Use:   "prompt",
+	Short: "Print the active profile for a shell prompt, starship or tmux",
+	Long: `Print a short segment describing the active profile, for use in PS1,
+starship or a tmux status line. It only reads local files (the environment,
+gsd's state, the AWS config and the SSO token cache) and never calls AWS,
+so it is fast enough to run on every prompt.
+
+--format is a Go template with these fields:
+
+  .Profile       the active profile
+  .Region        AWS_REGION, AWS_DEFAULT_REGION or the profile's region
+  .Account       the account ID from the config or the last 'gsd whoami'
+  .AccountAlias  the account alias, or the account name from 'config discover'
+  .Expiry        time left on the SSO token, e.g. 2h05m, or "expired"
+  .Tags          the profile's gsd_tags
+
+--colors maps tags to colors (black, red, green, yellow, blue, magenta, cyan,
+white); the segment takes the color of the profile's first mapped tag.
+--style wraps the color codes for where the segment is shown:
+
+  bash:      PS1='$(gsd prompt --style bash) \$ '
+  zsh:       setopt PROMPT_SUBST; PROMPT='$(gsd prompt --style zsh) %# '
+  tmux:      set -g status-right '#(gsd prompt --style tmux)'
+  starship:  [custom.aws] command = "gsd prompt" when = true`,
+	Args: cobra.NoArgs,
+	RunE: runResult(func(cmd *cobra.Command, args []string) (any, error) {
+		tmpl, err := template.New("prompt").Parse(promptFormat)
+		if err != nil {
+			return nil, output.Errorf(output.CodeInvalidArgument, "--format: %w", err)
+		}
+		colors, err := parsePromptColors(promptColors)
+		if err != nil {
+			return nil, output.Errorf(output.CodeInvalidArgument, "--colors: %w", err)
+		}
+		if !slices.Contains(promptStyles, promptStyle) {
+			return nil, output.Errorf(output.CodeInvalidArgument, "--style must be one of %s, not '%s'",
+				strings.Join(promptStyles, ", "), promptStyle)
+		}
+
+		segment, err := promptSegmentFor(promptProfile())
+		if err != nil {
+			return nil, err
+		}
+		for _, tag := range segment.Tags {
+			if color, ok := colors[tag]; ok {
+				segment.Color = color
+				break
+			}
+		}
+		segment.tmpl = tmpl
+
+		// Fields that don't exist only fail when the template runs.
+		if err := tmpl.Execute(io.Discard, segment); err != nil {
+			return nil, output.Errorf(output.CodeInvalidArgument, "--format: %w", err)
+		}
+		return segment, nil
+	}),
+}
+
+// promptProfile returns the profile chosen through AWS_PROFILE or a global
+// switch, or "" if neither; unlike activeProfile it doesn't assume default.
+func promptProfile() string {
+	if profile := os.Getenv("AWS_PROFILE"); profile != "" {
+		return profile
+	}
+	return state.GlobalProfile()
+}
+
+// promptSegment is the result of `gsd prompt`.
+type promptSegment struct {
+	Profile      string     `json:"profile" yaml:"profile"`
+	Region       string     `json:"region,omitempty" yaml:"region,omitempty"`
+	Account      string     `json:"account,omitempty" yaml:"account,omitempty"`
+	AccountAlias string     `json:"account_alias,omitempty" yaml:"account_alias,omitempty"`
+	Expiry       string     `json:"expiry,omitempty" yaml:"expiry,omitempty"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty" yaml:"expires_at,omitempty"`
+	Tags         []string   `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Color        string     `json:"color,omitempty" yaml:"color,omitempty"`
+
+	tmpl *template.Template
+}
+
+// promptSegmentFor gathers what the prompt shows about a profile from
+// local files only.
+func promptSegmentFor(name string) (*promptSegment, error) {
+	store, err := loadStore()
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		name = profiles.DefaultProfile
+		if !store.Has(name) {
+			return &promptSegment{}, nil
+		}
+	}
+
+	segment := &promptSegment{Profile: name}
+	segment.Region = os.Getenv("AWS_REGION")
+	if segment.Region == "" {
+		segment.Region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+
+	p, ok := store.Get(name)
+	if !ok {
+		// AWS_PROFILE names a profile that doesn't exist; show it anyway
+		// so the prompt doesn't hide the mistake.
+		return segment, nil
+	}
+	if segment.Region == "" {
+		segment.Region = p.Region
+	}
+	segment.Tags = p.Tags
+
+	segment.Account = p.AccountID()
+	if segment.Account == "" {
+		if id, ok := state.CachedIdentity(name); ok {
+			segment.Account = id.Account
+		}
+	}
+	if segment.Account != "" {
+		segment.AccountAlias = state.AccountAlias(segment.Account)
+	}
+
+	if login := ssoLoginProfile(store, name); login != nil {
+		if token, err := ssocache.LoadForProfile(login); err == nil {
+			if expiry, err := token.Expiry(); err == nil {
+				segment.ExpiresAt = &expiry
+				if token.Valid() {
+					segment.Expiry = ssocache.FormatRemaining(time.Until(expiry))
+				} else {
+					segment.Expiry = "expired"
+				}
+			}
+		}
+	}
+	return segment, nil
+}
+
+func (s *promptSegment) Text(w io.Writer) {
+	if s.Profile == "" {
+		return
+	}
+	var b strings.Builder
+	if err := s.tmpl.Execute(&b, s); err != nil {
+		return
+	}
+	fmt.Fprintln(w, colorize(b.String(), s.Color, promptStyle))
+}
+
+// colorize wraps text in the escape sequences for color, marked up so the
+// consumer doesn't count them towards the width of the prompt.
+func colorize(text, color, style string) string {
+	code, ok := ansiColors[color]
+	if !ok || text == "" {
+		return text
+	}
+	switch style {
+	case "none":
+		return text
+	case "bash":
+		return "\001\033[" + code + "m\002" + text + "\001\033[0m\002"
+	case "zsh":
+		return "%F{" + color + "}" + text + "%f"
+	case "tmux":
+		return "#[fg=" + color + "]" + text + "#[default]"
+	default:
+		return "\033[" + code + "m" + text + "\033[0m"
+	}
+}
+
+// parsePromptColors parses tag=color pairs separated by commas.
+func parsePromptColors(value string) (map[string]string, error) {
+	colors := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		tag, color, ok := strings.Cut(pair, "=")
+		tag, color = strings.TrimSpace(tag), strings.TrimSpace(color)
+		if !ok || tag == "" {
+			return nil, fmt.Errorf("expected tag=color, got '%s'", pair)
+		}
+		if _, ok := ansiColors[color]; !ok {
+			return nil, fmt.Errorf("unknown color '%s' for tag '%s'", color, tag)
+		}
+		colors[tag] = color
+	}
+	return colors, nil
+}
+
+func defaultPromptColorsValue() string {
+	if v := os.Getenv("GSD_PROMPT_COLORS"); v != "" {
+		return v
+	}
+	return defaultPromptColors
+}
+
+func init() {
+	promptCmd.Flags().StringVar(&promptFormat, "format", defaultPromptFormat, "Go template for the segment")
+	promptCmd.Flags().StringVar(&promptColors, "colors", defaultPromptColorsValue(), "Colors per tag, e.g. prod=red,dev=green; GSD_PROMPT_COLORS sets the default")
+	promptCmd.Flags().StringVar(&promptStyle, "style", "ansi", "Color markup: ansi, bash, zsh, tmux or none")
+
+	rootCmd.AddCommand(promptCmd)
+}
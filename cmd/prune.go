@@ -0,0 +1,329 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/aphexlog/gsd/internal/profiles"
+	"github.com/aphexlog/gsd/internal/ssoaccounts"
+	"github.com/aws/smithy-go"
+	"github.com/spf13/cobra"
+)
+
+var (
+	pruneDryRun             bool
+	pruneYes                bool
+	pruneIncludeUnreachable bool
+)
+
+// probeTimeout bounds the STS call made for each role profile.
+const probeTimeout = 20 * time.Second
+
+var configPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Find and remove profiles whose access no longer exists",
+	Long: `Check SSO profiles against the accounts and roles their SSO session can
+still reach, and role profiles by assuming the role through STS. Profiles
+whose account or role is gone are dead; profiles that could not be checked
+for another reason, such as expired source credentials, are unreachable.
+gsd lists both and asks which to remove. Only dead profiles are removed
+with --yes unless --include-unreachable is given.
+
+SSO sessions need a valid token from 'aws sso login'; profiles of sessions
+without one are skipped.`,
+	Args: cobra.NoArgs,
+	RunE: runResult(func(cmd *cobra.Command, args []string) (any, error) {
+		store, err := loadStore()
+		if err != nil {
+			return nil, err
+		}
+
+		result := checkProfiles(store)
+		result.DryRun = pruneDryRun
+		candidates := result.candidates()
+		if pruneDryRun || len(candidates) == 0 {
+			return result, nil
+		}
+
+		var selected []string
+		if pruneYes {
+			for _, item := range candidates {
+				if item.Status == pruneDead || pruneIncludeUnreachable {
+					selected = append(selected, item.Profile)
+				}
+			}
+			if len(selected) == 0 {
+				return result, nil
+			}
+		} else {
+			result.Text(os.Stderr)
+			selected, err = selectPrune(candidates)
+			if err != nil {
+				return nil, err
+			}
+			if len(selected) == 0 {
+				return cancelled()
+			}
+
+			var confirm bool
+			confirmPrompt := &survey.Confirm{
+				Message: fmt.Sprintf("⚠️  Are you sure you want to remove %d profile(s)?", len(selected)),
+				Default: false,
+			}
+			if err := askOne(confirmPrompt, &confirm); err != nil {
+				return nil, err
+			}
+			if !confirm {
+				return cancelled()
+			}
+		}
+
+		err = updateStore(func(store *profiles.Store) error {
+			for _, name := range selected {
+				store.Remove(name)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to save changes: %w", err)
+		}
+		forgetIdentities(selected...)
+		result.Removed = selected
+		return result, nil
+	}),
+}
+
+// Prune statuses.
+const (
+	pruneDead        = "dead"
+	pruneUnreachable = "unreachable"
+	pruneSkipped     = "skipped"
+)
+
+// checkProfiles checks every SSO and role profile and returns the ones that
+// are not healthy.
+func checkProfiles(store *profiles.Store) *pruneResult {
+	result := &pruneResult{}
+	bySession := make(map[string][]*profiles.Profile)
+	var sessions, roles []string
+	for _, p := range store.Profiles() {
+		switch {
+		case p.Type == profiles.TypeSSO && p.SSOSession == "":
+			result.add(p, pruneSkipped, "uses inline SSO settings instead of an sso-session")
+		case p.Type == profiles.TypeSSO:
+			if bySession[p.SSOSession] == nil {
+				sessions = append(sessions, p.SSOSession)
+			}
+			bySession[p.SSOSession] = append(bySession[p.SSOSession], p)
+		case p.Type.IsRole():
+			roles = append(roles, p.Name)
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "🔍 Checking %d SSO session(s) and %d role profile(s)...\n", len(sessions), len(roles))
+	for _, name := range sessions {
+		checkSSOSession(store, name, bySession[name], result)
+	}
+	for _, name := range roles {
+		checkRoleProfile(store, name, result)
+	}
+	return result
+}
+
+// checkSSOSession compares the session's profiles with the accounts and
+// roles its token can still reach.
+func checkSSOSession(store *profiles.Store, name string, users []*profiles.Profile, result *pruneResult) {
+	session, ok := store.GetSSOSession(name)
+	if !ok {
+		for _, p := range users {
+			result.add(p, pruneDead, fmt.Sprintf("sso_session '%s' does not exist", name))
+		}
+		return
+	}
+
+	ctx := context.TODO()
+	client, err := ssoClient(session)
+	var list []ssoaccounts.Account
+	if err == nil {
+		if list, err = client.Accounts(ctx); err != nil {
+			err = ssoError(session, err)
+		}
+	}
+	if err != nil {
+		for _, p := range users {
+			result.add(p, pruneSkipped, err.Error())
+		}
+		return
+	}
+
+	accounts := make(map[string]bool)
+	for _, acct := range list {
+		accounts[acct.ID] = true
+	}
+	roles := make(map[string]map[string]bool)
+	for _, p := range users {
+		if !accounts[p.SSOAccountID] {
+			result.add(p, pruneDead, fmt.Sprintf("account %s is no longer assigned", p.SSOAccountID))
+			continue
+		}
+		if roles[p.SSOAccountID] == nil {
+			names, err := client.Roles(ctx, p.SSOAccountID)
+			if err != nil {
+				result.add(p, pruneUnreachable, ssoError(session, err).Error())
+				continue
+			}
+			roles[p.SSOAccountID] = make(map[string]bool)
+			for _, role := range names {
+				roles[p.SSOAccountID][role] = true
+			}
+		}
+		if !roles[p.SSOAccountID][p.SSORoleName] {
+			result.add(p, pruneDead, fmt.Sprintf("role %s no longer exists in account %s", p.SSORoleName, p.SSOAccountID))
+			continue
+		}
+		result.Healthy++
+	}
+}
+
+// checkRoleProfile assumes the profile's role through STS.
+func checkRoleProfile(store *profiles.Store, name string, result *pruneResult) {
+	p, _ := store.Get(name)
+	if _, err := store.RoleChain(name); err != nil {
+		var missing *profiles.MissingSourceError
+		var cycle *profiles.CycleError
+		if errors.As(err, &missing) || errors.As(err, &cycle) {
+			result.add(p, pruneDead, err.Error())
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+	defer cancel()
+	_, err := callerIdentity(ctx, name)
+	switch {
+	case err == nil:
+		result.Healthy++
+	case isAccessDenied(err):
+		result.add(p, pruneDead, fmt.Sprintf("cannot assume %s", p.RoleARN))
+	default:
+		result.add(p, pruneUnreachable, probeError(err))
+	}
+}
+
+// isAccessDenied reports whether STS refused to let the profile assume its
+// role, which is what happens when the role is deleted or stops trusting
+// the caller.
+func isAccessDenied(err error) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && apiErr.ErrorCode() == "AccessDenied"
+}
+
+// probeError shortens a failed probe to the part worth showing in a table
+// row, without the SDK's operation and retry wrappers.
+func probeError(err error) string {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.ErrorCode() + ": " + apiErr.ErrorMessage()
+	}
+	var netErr *net.OpError
+	if errors.As(err, &netErr) {
+		return netErr.Error()
+	}
+	for next := errors.Unwrap(err); next != nil; next = errors.Unwrap(err) {
+		err = next
+	}
+	return err.Error()
+}
+
+// selectPrune asks which of the dead and unreachable profiles to remove.
+// Dead profiles are selected to begin with.
+func selectPrune(candidates []pruneItem) ([]string, error) {
+	options := make([]string, len(candidates))
+	var defaults []string
+	for i, item := range candidates {
+		options[i] = item.Profile
+		if item.Status == pruneDead {
+			defaults = append(defaults, item.Profile)
+		}
+	}
+	var selected []string
+	err := askFlag("--yes", &survey.MultiSelect{
+		Message: "Choose the profiles to remove:",
+		Options: options,
+		Default: defaults,
+		Description: func(value string, index int) string {
+			return candidates[index].Status + ": " + candidates[index].Reason
+		},
+		PageSize: 15,
+	}, &selected)
+	return selected, err
+}
+
+// pruneItem is a profile that failed its check or could not be checked.
+type pruneItem struct {
+	Profile string        `json:"profile" yaml:"profile"`
+	Type    profiles.Type `json:"type" yaml:"type"`
+	Status  string        `json:"status" yaml:"status"`
+	Reason  string        `json:"reason" yaml:"reason"`
+}
+
+// pruneResult is the result of `gsd config prune`.
+type pruneResult struct {
+	DryRun  bool        `json:"dry_run" yaml:"dry_run"`
+	Healthy int         `json:"healthy" yaml:"healthy"`
+	Items   []pruneItem `json:"items" yaml:"items"`
+	Removed []string    `json:"removed" yaml:"removed"`
+}
+
+func (r *pruneResult) add(p *profiles.Profile, status, reason string) {
+	r.Items = append(r.Items, pruneItem{Profile: p.Name, Type: p.Type, Status: status, Reason: reason})
+}
+
+// candidates returns the items that can be removed.
+func (r *pruneResult) candidates() []pruneItem {
+	var items []pruneItem
+	for _, item := range r.Items {
+		if item.Status != pruneSkipped {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func (r *pruneResult) Text(out io.Writer) {
+	if len(r.Removed) > 0 {
+		fmt.Fprintf(out, "🗑️  Removed %d profile(s):\n", len(r.Removed))
+		for _, name := range r.Removed {
+			fmt.Fprintf(out, "   - %s\n", name)
+		}
+		return
+	}
+	if len(r.Items) == 0 {
+		fmt.Fprintf(out, "✨ All %d checked profile(s) are healthy\n", r.Healthy)
+		return
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PROFILE\tTYPE\tSTATUS\tREASON")
+	for _, item := range r.Items {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", item.Profile, item.Type, item.Status, item.Reason)
+	}
+	w.Flush()
+	fmt.Fprintf(out, "\n%d healthy, %d to review\n", r.Healthy, len(r.candidates()))
+}
+
+func init() {
+	configPruneCmd.Flags().BoolVar(&pruneDryRun, "dry-run", false, "Only list the profiles that would be removed")
+	configPruneCmd.Flags().BoolVarP(&pruneYes, "yes", "y", false, "Remove dead profiles without asking")
+	configPruneCmd.Flags().BoolVar(&pruneIncludeUnreachable, "include-unreachable", false, "With --yes, also remove unreachable profiles")
+	configPruneCmd.Flags().StringVar(&ssoEndpointURL, "endpoint-url", "", "SSO portal endpoint (default: $"+ssoaccounts.EndpointEnv+" or the AWS endpoint)")
+
+	configCmd.AddCommand(configPruneCmd)
+}
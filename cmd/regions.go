@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/aphexlog/gsd/internal/output"
+	"github.com/aphexlog/gsd/internal/regions"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/spf13/cobra"
+)
+
+var (
+	regionsPartition string
+	regionsLive      bool
+)
+
+var regionsCmd = &cobra.Command{
+	Use:   "regions [search]",
+	Short: "List and search AWS regions",
+	Long: `List the regions gsd knows about, optionally filtered by a search term
+that matches the code or name ("gsd regions frankfurt").
+
+With --live the list comes from EC2 DescribeRegions for the active profile,
+including whether the account has opted in to each region. Regions that
+the built-in catalog does not know yet are remembered, so they can be used
+in 'gsd config add' and 'gsd config set' from then on.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runResult(func(cmd *cobra.Command, args []string) (any, error) {
+		switch regionsPartition {
+		case "", regions.PartitionAWS, regions.PartitionChina, regions.PartitionGov:
+		default:
+			return nil, output.Errorf(output.CodeInvalidArgument, "unknown --partition '%s' (use %s, %s or %s)",
+				regionsPartition, regions.PartitionAWS, regions.PartitionChina, regions.PartitionGov)
+		}
+
+		var list regionList
+		if regionsLive {
+			live, err := liveRegions(context.TODO())
+			if err != nil {
+				return nil, err
+			}
+			list = live
+		} else {
+			for _, r := range regions.All() {
+				list = append(list, regionListing{Region: r})
+			}
+		}
+
+		var matched regionList
+		for _, row := range list {
+			if regionsPartition != "" && row.Partition != regionsPartition {
+				continue
+			}
+			if len(args) == 1 && !row.Matches(args[0]) {
+				continue
+			}
+			matched = append(matched, row)
+		}
+		return matched, nil
+	}),
+}
+
+// liveRegions asks EC2 for every region of the active profile's partition
+// and remembers the ones the catalog is missing.
+func liveRegions(ctx context.Context) (regionList, error) {
+	profile := activeProfile()
+	cfg, err := loadAWSConfig(ctx, profile)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+
+	out, err := ec2.NewFromConfig(cfg).DescribeRegions(ctx, &ec2.DescribeRegionsInput{AllRegions: aws.Bool(true)})
+	if err != nil {
+		return nil, output.Errorf(output.CodeAWS, "failed to describe regions for profile '%s': %w", profile, err)
+	}
+
+	var list regionList
+	var found []regions.Region
+	for _, r := range out.Regions {
+		code := aws.ToString(r.RegionName)
+		status := aws.ToString(r.OptInStatus)
+		region, ok := regions.Lookup(code)
+		if !ok {
+			region = regions.Region{
+				Code:      code,
+				Partition: regions.PartitionOf(code),
+				OptIn:     status != "opt-in-not-required",
+			}
+			found = append(found, region)
+		}
+		list = append(list, regionListing{Region: region, Status: status})
+	}
+
+	added, err := regions.Learn(found)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "🤖 Note: Could not remember new regions: %v\n", err)
+	} else if added > 0 {
+		fmt.Fprintf(os.Stderr, "✨ Added %d new region(s) to the catalog\n", added)
+	}
+	return list, nil
+}
+
+// regionListing is one row of `gsd regions`. Status is the account's
+// opt-in status and is only known with --live.
+type regionListing struct {
+	regions.Region `yaml:",inline"`
+	Status         string `json:"status,omitempty" yaml:"status,omitempty"`
+}
+
+type regionList []regionListing
+
+func (rows regionList) Text(out io.Writer) {
+	if len(rows) == 0 {
+		fmt.Fprintln(out, "🤖 No matching regions")
+		return
+	}
+
+	live := rows[0].Status != ""
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	if live {
+		fmt.Fprintln(w, "CODE\tNAME\tPARTITION\tSTATUS")
+	} else {
+		fmt.Fprintln(w, "CODE\tNAME\tPARTITION\tOPT-IN")
+	}
+	for _, row := range rows {
+		last := "-"
+		if live {
+			last = row.Status
+		} else if row.OptIn {
+			last = "yes"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", row.Code, row.Name, row.Partition, last)
+	}
+	w.Flush()
+}
+
+func init() {
+	regionsCmd.Flags().StringVar(&regionsPartition, "partition", "", "Only list regions in this partition (aws, aws-cn or aws-us-gov)")
+	regionsCmd.Flags().BoolVar(&regionsLive, "live", false, "Ask EC2 for the regions available to the active profile")
+
+	rootCmd.AddCommand(regionsCmd)
+}
@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/aphexlog/gsd/internal/output"
+	"github.com/aphexlog/gsd/internal/pin"
+	"github.com/aphexlog/gsd/internal/profiles"
+	"github.com/aphexlog/gsd/internal/state"
+	"github.com/spf13/cobra"
+)
+
+var configRenameCmd = &cobra.Command{
+	Use:   "rename <old> <new>",
+	Short: "Rename a profile and update everything that refers to it",
+	Long: `Rename a profile in both the config and credentials files. Role profiles
+whose source_profile names it are updated, as are gsd's switch history and
+identity cache.
+Pin files that still name the old profile are listed so they can be
+updated; gsd doesn't edit files that may be shared through a repository.`,
+	Args: cobra.ExactArgs(2),
+	RunE: runResult(func(cmd *cobra.Command, args []string) (any, error) {
+		from, to := args[0], args[1]
+		if err := validateProfileName(to); err != nil {
+			return nil, err
+		}
+
+		var updated []string
+		err := updateStore(func(store *profiles.Store) error {
+			if err := checkCopyTarget(store, from, to); err != nil {
+				return err
+			}
+			var err error
+			updated, err = store.Rename(from, to)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if err := state.RenameSwitches(from, to); err != nil {
+			fmt.Fprintf(os.Stderr, "🤖 Note: Could not update switch history: %v\n", err)
+		}
+		if err := state.RenameIdentity(from, to); err != nil {
+			fmt.Fprintf(os.Stderr, "🤖 Note: Could not update the identity cache: %v\n", err)
+		}
+		if os.Getenv("AWS_PROFILE") == from {
+			fmt.Fprintf(os.Stderr, "ℹ️  AWS_PROFILE in this shell still names '%s'\n", from)
+		}
+
+		return &renameResult{
+			Action:          "renamed",
+			From:            from,
+			To:              to,
+			UpdatedProfiles: updated,
+			StalePins:       pinsNaming(from),
+		}, nil
+	}),
+}
+
+var configCloneCmd = &cobra.Command{
+	Use:   "clone <source> <new>",
+	Short: "Copy a profile to a new name",
+	Args:  cobra.ExactArgs(2),
+	RunE: runResult(func(cmd *cobra.Command, args []string) (any, error) {
+		from, to := args[0], args[1]
+		if err := validateProfileName(to); err != nil {
+			return nil, err
+		}
+
+		err := updateStore(func(store *profiles.Store) error {
+			if err := checkCopyTarget(store, from, to); err != nil {
+				return err
+			}
+			return store.Clone(from, to)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return &renameResult{Action: "cloned", From: from, To: to}, nil
+	}),
+}
+
+// pinsNaming returns the pin files that name profile: those allowed with
+// 'gsd allow' and the one that applies to the working directory.
+func pinsNaming(profile string) []string {
+	paths, _ := state.TrustedPaths()
+	if f, err := pin.Find("."); err == nil && f != nil && !slices.Contains(paths, f.Path) {
+		paths = append(paths, f.Path)
+	}
+
+	var stale []string
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		if f, err := pin.Parse(path, data); err == nil && f.Profile == profile {
+			stale = append(stale, path)
+		}
+	}
+	return stale
+}
+
+// checkCopyTarget reports a missing source or an existing destination with
+// the matching error codes.
+func checkCopyTarget(store *profiles.Store, from, to string) error {
+	if !store.Has(from) {
+		return output.Errorf(output.CodeNotFound, "profile '%s' not found", from)
+	}
+	if store.Has(to) {
+		return output.Errorf(output.CodeConflict, "profile '%s' already exists", to)
+	}
+	return nil
+}
+
+// checkNewProfileName rejects a name that is invalid or already taken.
+func checkNewProfileName(store *profiles.Store, name string) error {
+	if err := validateProfileName(name); err != nil {
+		return err
+	}
+	if store.Has(name) {
+		return output.Errorf(output.CodeConflict, "profile '%s' already exists", name)
+	}
+	return nil
+}
+
+// validateProfileName is profiles.ValidateName with the error code for a bad
+// argument.
+func validateProfileName(name string) error {
+	if err := profiles.ValidateName(name); err != nil {
+		return output.Errorf(output.CodeInvalidArgument, "%w", err)
+	}
+	return nil
+}
+
+// renameResult is the result of config rename and config clone.
+type renameResult struct {
+	Action          string   `json:"action" yaml:"action"`
+	From            string   `json:"from" yaml:"from"`
+	To              string   `json:"to" yaml:"to"`
+	UpdatedProfiles []string `json:"updated_profiles,omitempty" yaml:"updated_profiles,omitempty"`
+	StalePins       []string `json:"stale_pins,omitempty" yaml:"stale_pins,omitempty"`
+}
+
+func (r *renameResult) Text(w io.Writer) {
+	if r.Action == "cloned" {
+		fmt.Fprintf(w, "✨ Profile '%s' cloned to '%s'\n", r.From, r.To)
+		return
+	}
+	fmt.Fprintf(w, "✨ Profile '%s' renamed to '%s'\n", r.From, r.To)
+	if len(r.UpdatedProfiles) > 0 {
+		fmt.Fprintf(w, "   Updated source_profile in: %s\n", strings.Join(r.UpdatedProfiles, ", "))
+	}
+	for _, path := range r.StalePins {
+		fmt.Fprintf(w, "⚠️  %s still pins '%s'; change it to '%s' and run 'gsd allow'\n", path, r.From, r.To)
+	}
+}
+
+func init() {
+	configCmd.AddCommand(configRenameCmd)
+	configCmd.AddCommand(configCloneCmd)
+}
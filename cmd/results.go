@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+)
+
+// cancelledResult is returned when the user backs out of a prompt.
+type cancelledResult struct {
+	Cancelled bool `json:"cancelled" yaml:"cancelled"`
+}
+
+func (r cancelledResult) Text(w io.Writer) {
+	fmt.Fprintln(w, "🤖 Operation cancelled")
+}
+
+func cancelled() (any, error) {
+	return cancelledResult{Cancelled: true}, nil
+}
+
+// profileResult reports a change to a single profile.
+type profileResult struct {
+	Action  string `json:"action" yaml:"action"`
+	Profile string `json:"profile" yaml:"profile"`
+}
+
+func (r *profileResult) Text(w io.Writer) {
+	switch r.Action {
+	case "created":
+		fmt.Fprintf(w, "✨ Profile '%s' created successfully!\n", r.Profile)
+	case "updated":
+		fmt.Fprintf(w, "✨ Profile '%s' updated successfully!\n", r.Profile)
+	case "removed":
+		fmt.Fprintf(w, "✨ Profile '%s' has been removed\n", r.Profile)
+	default:
+		fmt.Fprintf(w, "✨ Profile '%s' %s\n", r.Profile, r.Action)
+	}
+}
+
+// ssoSessionResult reports a change to an sso-session block.
+type ssoSessionResult struct {
+	Action          string   `json:"action" yaml:"action"`
+	Session         string   `json:"session" yaml:"session"`
+	RemovedProfiles []string `json:"removed_profiles,omitempty" yaml:"removed_profiles,omitempty"`
+}
+
+func (r *ssoSessionResult) Text(w io.Writer) {
+	switch r.Action {
+	case "created":
+		fmt.Fprintf(w, "✨ SSO session '%s' created successfully!\n", r.Session)
+	case "updated":
+		fmt.Fprintf(w, "✨ SSO session '%s' updated successfully!\n", r.Session)
+	default:
+		fmt.Fprintf(w, "✨ SSO session '%s' has been removed\n", r.Session)
+	}
+}
@@ -0,0 +1,221 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/aphexlog/gsd/internal/output"
+	"github.com/aphexlog/gsd/internal/profiles"
+)
+
+// credentialSources are the values the SDKs accept for credential_source.
+var credentialSources = []string{"Environment", "Ec2InstanceMetadata", "EcsContainer"}
+
+// roleOptionalSettings are the assume-role keys that may be left unset.
+var roleOptionalSettings = []struct {
+	Key      string
+	Message  string
+	Help     string
+	Validate survey.Validator
+}{
+	{"mfa_serial", "MFA device ARN (optional):", "ARN or serial of the MFA device required to assume the role", nil},
+	{"external_id", "External ID (optional):", "External ID the role's trust policy requires, if any", nil},
+	{"role_session_name", "Role session name (optional):", "Name that appears in CloudTrail for sessions of this role", nil},
+	{"duration_seconds", "Session duration in seconds (optional):", "Between 900 and 43200; the role's maximum applies", validateDurationSeconds},
+}
+
+// askRoleSettings prompts for the settings of a role profile of type t,
+// using p's current values as defaults. The result maps config keys to
+// values; an empty value means the key should be removed.
+func askRoleSettings(store *profiles.Store, t profiles.Type, p *profiles.Profile) (map[string]string, error) {
+	settings := make(map[string]string)
+
+	roleARN, err := askRoleARN(p)
+	if err != nil {
+		return nil, err
+	}
+	settings["role_arn"] = roleARN
+
+	// A role profile gets its base credentials from exactly one place.
+	settings["source_profile"] = ""
+	settings["credential_source"] = ""
+	settings["web_identity_token_file"] = ""
+
+	switch t {
+	case profiles.TypeAssumeRole:
+		var sources []string
+		for _, name := range store.Names() {
+			if name != p.Name {
+				sources = append(sources, name)
+			}
+		}
+		if len(sources) == 0 {
+			return nil, output.Errorf(output.CodeNotFound, "no other profiles exist to use as the source profile")
+		}
+		source := p.SourceProfile
+		err := askOne(&survey.Select{
+			Message: "Source profile:",
+			Options: sources,
+			Default: defaultOption(sources, p.SourceProfile),
+			Help:    "Profile whose credentials are used to assume the role",
+		}, &source)
+		if err != nil {
+			return nil, err
+		}
+		settings["source_profile"] = source
+
+	case profiles.TypeCredentialSource:
+		source := p.CredentialSource
+		err := askOne(&survey.Select{
+			Message: "Credential source:",
+			Options: credentialSources,
+			Default: defaultOption(credentialSources, p.CredentialSource),
+			Help:    "Where the base credentials come from when running on AWS",
+		}, &source)
+		if err != nil {
+			return nil, err
+		}
+		settings["credential_source"] = source
+
+	case profiles.TypeWebIdentity:
+		tokenFile := p.WebIdentityTokenFile
+		err := askOne(&survey.Input{
+			Message: "Web identity token file:",
+			Default: p.WebIdentityTokenFile,
+		}, &tokenFile, survey.WithValidator(survey.Required))
+		if err != nil {
+			return nil, err
+		}
+		settings["web_identity_token_file"] = tokenFile
+	}
+
+	current := map[string]string{
+		"mfa_serial":        p.MFASerial,
+		"external_id":       p.ExternalID,
+		"role_session_name": p.RoleSessionName,
+		"duration_seconds":  p.DurationSeconds,
+	}
+	for _, setting := range roleOptionalSettings {
+		value := current[setting.Key]
+		var opts []survey.AskOpt
+		if setting.Validate != nil {
+			opts = append(opts, survey.WithValidator(setting.Validate))
+		}
+		err := askOne(&survey.Input{
+			Message: setting.Message,
+			Default: current[setting.Key],
+			Help:    setting.Help,
+		}, &value, opts...)
+		if err != nil {
+			return nil, err
+		}
+		settings[setting.Key] = strings.TrimSpace(value)
+	}
+	return settings, nil
+}
+
+// roleKeys is the order role settings are written in.
+var roleKeys = []string{
+	"role_arn", "source_profile", "credential_source", "web_identity_token_file",
+	"mfa_serial", "external_id", "role_session_name", "duration_seconds",
+}
+
+const (
+	roleARNEnter = "Enter the role ARN"
+	roleARNBuild = "Build it from an account ID and role name"
+)
+
+// askRoleARN asks for the role to assume. New profiles may build the ARN
+// from an account ID and role name instead of pasting it.
+func askRoleARN(p *profiles.Profile) (string, error) {
+	mode := roleARNEnter
+	if p.RoleARN == "" {
+		err := askOne(&survey.Select{
+			Message: "How do you want to specify the role?",
+			Options: []string{roleARNEnter, roleARNBuild},
+		}, &mode)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if mode == roleARNBuild {
+		answers := struct {
+			AccountID string
+			RoleName  string
+		}{}
+		err := ask([]*survey.Question{
+			{
+				Name:     "AccountID",
+				Prompt:   &survey.Input{Message: "AWS Account ID:", Help: "Enter the 12-digit ID of the account that owns the role"},
+				Validate: validateAccountID,
+			},
+			{
+				Name:     "RoleName",
+				Prompt:   &survey.Input{Message: "Role name:", Help: "Name of the IAM role, optionally with its path"},
+				Validate: survey.Required,
+			},
+		}, &answers)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("arn:%s:iam::%s:role/%s", partitionForRegion(p.Region), answers.AccountID, strings.TrimPrefix(answers.RoleName, "/")), nil
+	}
+
+	roleARN := p.RoleARN
+	err := askOne(&survey.Input{
+		Message: "Role ARN:",
+		Default: p.RoleARN,
+		Help:    "e.g. arn:aws:iam::123456789012:role/Admin",
+	}, &roleARN, survey.WithValidator(validateRoleARN))
+	return roleARN, err
+}
+
+// partitionForRegion returns the ARN partition a region belongs to.
+func partitionForRegion(region string) string {
+	switch {
+	case strings.HasPrefix(region, "cn-"):
+		return "aws-cn"
+	case strings.HasPrefix(region, "us-gov-"):
+		return "aws-us-gov"
+	default:
+		return "aws"
+	}
+}
+
+// applySettings writes role settings to a profile, removing empty keys.
+func applySettings(store *profiles.Store, name string, settings map[string]string) {
+	for _, key := range roleKeys {
+		value, ok := settings[key]
+		if !ok {
+			continue
+		}
+		if value == "" {
+			store.DeleteConfigKey(name, key)
+		} else {
+			store.SetConfig(name, key, value)
+		}
+	}
+}
+
+func validateRoleARN(val interface{}) error {
+	str, _ := val.(string)
+	if !strings.HasPrefix(str, "arn:") || !strings.Contains(str, ":role/") {
+		return fmt.Errorf("expected an IAM role ARN like arn:aws:iam::123456789012:role/Name")
+	}
+	return nil
+}
+
+func validateDurationSeconds(val interface{}) error {
+	str, _ := val.(string)
+	if str == "" {
+		return nil
+	}
+	n, err := strconv.Atoi(str)
+	if err != nil || n < 900 || n > 43200 {
+		return fmt.Errorf("duration must be a whole number of seconds between 900 and 43200")
+	}
+	return nil
+}
@@ -1,11 +1,26 @@
 package cmd
 
 import (
+	"errors"
 	"os"
+	"strings"
 
+	"github.com/aphexlog/gsd/internal/output"
 	"github.com/spf13/cobra"
 )
 
+var (
+	configFileFlag      string
+	credentialsFileFlag string
+	outputFlag          string
+
+	// commandLine is the running command, recorded in history snapshots.
+	commandLine string
+
+	// renderer prints command results in the format chosen with --output.
+	renderer = &output.Renderer{Format: output.Text, Out: os.Stdout, Err: os.Stderr}
+)
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "gsd",
@@ -13,18 +28,83 @@ var rootCmd = &cobra.Command{
 	Long: `🤖 GSD (Get Stuff Done) - Your AWS Profile Assistant
 A friendly tool for managing AWS profiles and services.
 Making AWS profile management simple and efficient.`,
+	SilenceErrors: true,
+	SilenceUsage:  true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		commandLine = strings.TrimSpace(cmd.CommandPath() + " " + strings.Join(args, " "))
+
+		format, err := output.ParseFormat(outputFlag)
+		if err != nil {
+			return err
+		}
+		renderer.Format = format
+		return nil
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
 	err := rootCmd.Execute()
-	if err != nil {
+	if err == nil {
+		return
+	}
+
+	// Flag parsing fails before PersistentPreRunE, so pick up --output here too.
+	if format, ferr := output.ParseFormat(outputFlag); ferr == nil {
+		renderer.Format = format
+	}
+
+	if errors.Is(err, errReported) {
 		os.Exit(1)
 	}
+
+	var e *output.Error
+	if errors.As(err, &e) && e.Code == output.CodeCancelled && renderer.Format == output.Text {
+		renderer.Render(cancelledResult{})
+		os.Exit(0)
+	}
+	renderer.RenderError(err)
+	os.Exit(1)
+}
+
+// failer is implemented by results that describe a failure themselves, such
+// as a doctor report with failed checks, so gsd exits non-zero after
+// printing them.
+type failer interface {
+	Failed() bool
+}
+
+// errReported makes gsd exit non-zero without printing anything more.
+var errReported = errors.New("failure already reported")
+
+// runResult adapts a command that produces a typed result to cobra, printing
+// the result with the shared renderer.
+func runResult(fn func(cmd *cobra.Command, args []string) (any, error)) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		result, err := fn(cmd, args)
+		if err != nil {
+			return err
+		}
+		if err := renderer.Render(result); err != nil {
+			return err
+		}
+		if f, ok := result.(failer); ok && f.Failed() {
+			return errReported
+		}
+		return nil
+	}
 }
 
 func init() {
 	// Hide the completion command
 	rootCmd.CompletionOptions.HiddenDefaultCmd = true
+
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return output.Errorf(output.CodeInvalidArgument, "%w", err)
+	})
+
+	rootCmd.PersistentFlags().StringVar(&configFileFlag, "config-file", "", "AWS config file (defaults to $AWS_CONFIG_FILE or ~/.aws/config)")
+	rootCmd.PersistentFlags().StringVar(&credentialsFileFlag, "credentials-file", "", "AWS credentials file (defaults to $AWS_SHARED_CREDENTIALS_FILE or ~/.aws/credentials)")
+	rootCmd.PersistentFlags().StringVarP(&outputFlag, "output", "o", "text", "Output format: text, json or yaml")
 }
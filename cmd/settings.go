@@ -0,0 +1,249 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/aphexlog/gsd/internal/output"
+	"github.com/aphexlog/gsd/internal/profiles"
+	"github.com/aphexlog/gsd/internal/regions"
+	"github.com/spf13/cobra"
+)
+
+var (
+	settingSSOSession bool
+	settingServices   bool
+)
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <profile> <key>",
+	Short: "Print a single setting of a profile",
+	Long: `Print a single setting of a profile, SSO session or services section.
+Credential keys are read from the credentials file, everything else from the
+config file. Nested settings are written as parent.key.
+
+  gsd config get dev region
+  gsd config get dev s3.max_concurrent_requests
+  gsd config get --sso-session corp sso_start_url`,
+	Args: cobra.ExactArgs(2),
+	RunE: runResult(func(cmd *cobra.Command, args []string) (any, error) {
+		store, err := loadStore()
+		if err != nil {
+			return nil, err
+		}
+		target, err := settingTarget(store, args[0])
+		if err != nil {
+			return nil, err
+		}
+
+		key := args[1]
+		value, file, ok := store.Setting(target, key)
+		if !ok {
+			return nil, output.Errorf(output.CodeNotFound, "%s is not set in %s", key, target)
+		}
+		return &settingResult{Section: target.String(), Key: key, Value: value, File: file}, nil
+	}),
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <profile> <key> <value>",
+	Short: "Change a single setting of a profile",
+	Long: `Change a single setting of a profile, SSO session or services section.
+Credential keys go to the credentials file, everything else to the config
+file. Known keys are validated. Services sections are created as needed.
+
+  gsd config set dev output table
+  gsd config set dev duration_seconds 3600
+  gsd config set --services local-dynamo dynamodb.endpoint_url http://localhost:8000`,
+	Args: cobra.ExactArgs(3),
+	RunE: runResult(func(cmd *cobra.Command, args []string) (any, error) {
+		key, value := args[1], args[2]
+		if err := validateSetting(key, value); err != nil {
+			return nil, output.Errorf(output.CodeInvalidArgument, "invalid %s: %w", key, err)
+		}
+		if profiles.IsCredentialKey(key) {
+			// Keep secrets out of the history summary.
+			commandLine = strings.TrimSuffix(commandLine, value) + "****"
+		}
+
+		var target profiles.Target
+		var file string
+		err := updateStore(func(store *profiles.Store) error {
+			var err error
+			if target, err = settingTarget(store, args[0]); err != nil {
+				return err
+			}
+			if file, err = store.SetSetting(target, key, value); err != nil {
+				return output.Errorf(output.CodeInvalidArgument, "%w", err)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return &settingResult{Action: "set", Section: target.String(), Key: key, File: file}, nil
+	}),
+}
+
+var configUnsetCmd = &cobra.Command{
+	Use:   "unset <profile> <key>",
+	Short: "Remove a single setting from a profile",
+	Args:  cobra.ExactArgs(2),
+	RunE: runResult(func(cmd *cobra.Command, args []string) (any, error) {
+		key := args[1]
+
+		var target profiles.Target
+		var file string
+		err := updateStore(func(store *profiles.Store) error {
+			var err error
+			if target, err = settingTarget(store, args[0]); err != nil {
+				return err
+			}
+			var ok bool
+			if file, ok = store.UnsetSetting(target, key); !ok {
+				return output.Errorf(output.CodeNotFound, "%s is not set in %s", key, target)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return &settingResult{Action: "unset", Section: target.String(), Key: key, File: file}, nil
+	}),
+}
+
+// settingTarget picks the section named by the first argument. Profiles and
+// SSO sessions must already exist; services sections are created by set.
+func settingTarget(store *profiles.Store, name string) (profiles.Target, error) {
+	target := profiles.Target{Kind: profiles.ProfileSection, Name: name}
+	switch {
+	case settingSSOSession:
+		target.Kind = profiles.SSOSessionSection
+	case settingServices:
+		target.Kind = profiles.ServicesSection
+		return target, nil
+	}
+	if !store.Exists(target) {
+		return target, output.Errorf(output.CodeNotFound, "%s %s not found", target.Kind, name)
+	}
+	return target, nil
+}
+
+// settingResult is the result of config get, set and unset.
+type settingResult struct {
+	Action  string `json:"action,omitempty" yaml:"action,omitempty"`
+	Section string `json:"section" yaml:"section"`
+	Key     string `json:"key" yaml:"key"`
+	Value   string `json:"value,omitempty" yaml:"value,omitempty"`
+	File    string `json:"file" yaml:"file"`
+}
+
+func (r *settingResult) Text(w io.Writer) {
+	switch r.Action {
+	case "set":
+		fmt.Fprintf(w, "✨ Set %s in %s (%s)\n", r.Key, r.Section, r.File)
+	case "unset":
+		fmt.Fprintf(w, "✨ Removed %s from %s (%s)\n", r.Key, r.Section, r.File)
+	default:
+		// Just the value, so `$(gsd config get ...)` works.
+		fmt.Fprintln(w, r.Value)
+	}
+}
+
+var regionPattern = regexp.MustCompile(`^[a-z]{2}(-[a-z]+)+-\d+$`)
+
+// settingValidators check the values of well-known keys. Unknown keys are
+// written as given.
+var settingValidators = map[string]func(string) error{
+	"region":                          validateRegion,
+	"sso_region":                      validateRegion,
+	"sso_account_id":                  func(v string) error { return validateAccountID(v) },
+	"role_arn":                        func(v string) error { return validateRoleARN(v) },
+	"duration_seconds":                func(v string) error { return validateDurationSeconds(v) },
+	"max_attempts":                    validateInt(1, 100),
+	"metadata_service_timeout":        validateInt(0, 3600),
+	"metadata_service_num_attempts":   validateInt(1, 100),
+	"use_dualstack_endpoint":          validateBool,
+	"use_fips_endpoint":               validateBool,
+	"ignore_configured_endpoint_urls": validateBool,
+	"disable_request_compression":     validateBool,
+	"endpoint_url":                    validateURL,
+	"output":                          validateOneOf("json", "yaml", "yaml-stream", "text", "table"),
+	"retry_mode":                      validateOneOf("standard", "legacy", "adaptive"),
+	"sts_regional_endpoints":          validateOneOf("legacy", "regional"),
+	"defaults_mode":                   validateOneOf("standard", "in-region", "cross-region", "mobile", "auto", "legacy"),
+	"credential_source":               validateOneOf(credentialSources...),
+}
+
+// validateSetting checks value if key is one gsd knows about. Nested keys
+// are checked by their last part, e.g. dynamodb.endpoint_url as a URL.
+func validateSetting(key, value string) error {
+	if i := strings.LastIndex(key, "."); i >= 0 {
+		key = key[i+1:]
+	}
+	if validate, ok := settingValidators[key]; ok {
+		return validate(value)
+	}
+	return nil
+}
+
+func validateRegion(v string) error {
+	if !regionPattern.MatchString(v) {
+		return fmt.Errorf("'%s' is not an AWS region name like us-east-1", v)
+	}
+	if _, ok := regions.Lookup(v); !ok {
+		return fmt.Errorf("unknown region '%s' (see 'gsd regions', or 'gsd regions --live' if it is new)", v)
+	}
+	return nil
+}
+
+func validateInt(min, max int) func(string) error {
+	return func(v string) error {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < min || n > max {
+			return fmt.Errorf("expected a whole number between %d and %d", min, max)
+		}
+		return nil
+	}
+}
+
+func validateBool(v string) error {
+	if !strings.EqualFold(v, "true") && !strings.EqualFold(v, "false") {
+		return fmt.Errorf("expected true or false")
+	}
+	return nil
+}
+
+func validateURL(v string) error {
+	u, err := url.Parse(v)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("expected a URL like https://example.com")
+	}
+	return nil
+}
+
+func validateOneOf(values ...string) func(string) error {
+	return func(v string) error {
+		for _, allowed := range values {
+			if v == allowed {
+				return nil
+			}
+		}
+		return fmt.Errorf("expected one of %s", strings.Join(values, ", "))
+	}
+}
+
+func init() {
+	for _, cmd := range []*cobra.Command{configGetCmd, configSetCmd, configUnsetCmd} {
+		cmd.Flags().BoolVar(&settingSSOSession, "sso-session", false, "Target an [sso-session] block instead of a profile")
+		cmd.Flags().BoolVar(&settingServices, "services", false, "Target a [services] block instead of a profile")
+		cmd.MarkFlagsMutuallyExclusive("sso-session", "services")
+		configCmd.AddCommand(cmd)
+	}
+}
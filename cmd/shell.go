@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/aphexlog/gsd/internal/output"
+	"github.com/aphexlog/gsd/internal/profiles"
+	"github.com/aphexlog/gsd/internal/shell"
+	"github.com/spf13/cobra"
+)
+
+var (
+	shellInitExportRegion bool
+	shellInitNoHook       bool
+)
+
+var shellInitCmd = &cobra.Command{
+	Use:   "shell-init <bash|zsh|fish|powershell>",
+	Short: "Print the shell function that lets gsd switch profiles per shell",
+	Long: `Print a gsd wrapper function for your shell. Through it, 'gsd switch'
+exports AWS_PROFILE in the current shell only instead of rewriting the
+[default] profile for every terminal. Add it to your shell's startup file:
+
+  bash:        eval "$(gsd shell-init bash)"          # ~/.bashrc
+  zsh:         eval "$(gsd shell-init zsh)"           # ~/.zshrc
+  fish:        gsd shell-init fish | source           # ~/.config/fish/config.fish
+  PowerShell:  gsd shell-init powershell | Out-String | Invoke-Expression   # $PROFILE
+
+With --export-region the wrapper also exports AWS_REGION from the profile.
+
+The integration also activates profiles pinned with 'gsd pin' as you cd
+into a directory, and restores the previous one when you leave. Pass
+--no-hook to leave that out.`,
+	Args:      cobra.ExactArgs(1),
+	ValidArgs: shell.Shells,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		script, err := shell.Init(args[0], shellInitExportRegion, !shellInitNoHook)
+		if err != nil {
+			return output.Errorf(output.CodeInvalidArgument, "%w", err)
+		}
+		fmt.Fprint(cmd.OutOrStdout(), script)
+		return nil
+	},
+}
+
+// exportProfile sets AWS_PROFILE in the calling shell through the wrapper,
+// and AWS_REGION when the wrapper was set up with --export-region. The
+// profile being replaced is kept for `gsd switch -`. It returns the
+// exported region, if any.
+func exportProfile(store *profiles.Store, name, previous string) (string, error) {
+	vars := map[string]string{"AWS_PROFILE": name}
+	if previous != name {
+		vars[shell.PreviousVar] = previous
+	}
+	region := ""
+	if shell.ExportRegion() {
+		if p, ok := store.Get(name); ok {
+			region = p.Region
+		}
+		// An empty value clears the region of the previous profile.
+		vars["AWS_REGION"] = region
+	}
+	if err := shell.Export(vars); err != nil {
+		return "", fmt.Errorf("unable to update the shell environment: %w", err)
+	}
+	return region, nil
+}
+
+// errNoShellIntegration explains how to switch without the wrapper.
+var errNoShellIntegration = output.Errorf(output.CodeInvalidArgument,
+	"shell integration is not set up, so gsd can't set AWS_PROFILE in this shell; "+
+		"add 'eval \"$(gsd shell-init bash)\"' (or zsh, fish, powershell) to your shell's startup file, "+
+		"or pass --global to switch the [default] profile for every shell")
+
+func init() {
+	shellInitCmd.Flags().BoolVar(&shellInitExportRegion, "export-region", false, "Also export AWS_REGION from the selected profile")
+	shellInitCmd.Flags().BoolVar(&shellInitNoHook, "no-hook", false, "Don't activate pinned profiles when changing directory")
+
+	rootCmd.AddCommand(shellInitCmd)
+}
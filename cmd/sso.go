@@ -0,0 +1,232 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/aphexlog/gsd/internal/output"
+	"github.com/aphexlog/gsd/internal/profiles"
+	"github.com/aphexlog/gsd/internal/ssocache"
+	"github.com/spf13/cobra"
+)
+
+var ssoCmd = &cobra.Command{
+	Use:   "sso",
+	Short: "Manage IAM Identity Center (SSO) sessions",
+}
+
+var ssoLsCmd = &cobra.Command{
+	Use:   "ls",
+	Short: "List SSO sessions",
+	RunE: runResult(func(cmd *cobra.Command, args []string) (any, error) {
+		store, err := loadStore()
+		if err != nil {
+			return nil, err
+		}
+
+		rows := ssoSessionList{}
+		for _, sess := range store.SSOSessions() {
+			rows = append(rows, ssoSessionListing{
+				Name:               sess.Name,
+				StartURL:           sess.StartURL,
+				Region:             sess.Region,
+				RegistrationScopes: sess.RegistrationScopes,
+				Profiles:           store.ProfilesUsingSSOSession(sess.Name),
+				Token:              ssocache.Status(ssocache.Load(sess.Name)),
+			})
+		}
+		return rows, nil
+	}),
+}
+
+// ssoSessionListing is one row of `gsd sso ls`.
+type ssoSessionListing struct {
+	Name               string   `json:"name" yaml:"name"`
+	StartURL           string   `json:"start_url" yaml:"start_url"`
+	Region             string   `json:"region" yaml:"region"`
+	RegistrationScopes string   `json:"registration_scopes,omitempty" yaml:"registration_scopes,omitempty"`
+	Profiles           []string `json:"profiles" yaml:"profiles"`
+	Token              string   `json:"token" yaml:"token"`
+}
+
+type ssoSessionList []ssoSessionListing
+
+func (rows ssoSessionList) Text(out io.Writer) {
+	if len(rows) == 0 {
+		fmt.Fprintln(out, "🤖 No SSO sessions configured. Add one with 'gsd sso add'")
+		return
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSTART URL\tREGION\tSCOPES\tPROFILES\tTOKEN")
+	for _, row := range rows {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\n",
+			row.Name, row.StartURL, row.Region, row.RegistrationScopes, len(row.Profiles), row.Token)
+	}
+	w.Flush()
+}
+
+var ssoAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Add an SSO session",
+	RunE: runResult(func(cmd *cobra.Command, args []string) (any, error) {
+		store, err := loadStore()
+		if err != nil {
+			return nil, err
+		}
+
+		session := &profiles.SSOSession{
+			Region:             "us-east-1",
+			RegistrationScopes: profiles.DefaultRegistrationScopes,
+		}
+		if err := askSSOSessionSettings(store, session, true); err != nil {
+			return nil, err
+		}
+
+		err = updateStore(func(store *profiles.Store) error {
+			if _, exists := store.GetSSOSession(session.Name); exists {
+				return output.Errorf(output.CodeConflict, "SSO session '%s' already exists", session.Name)
+			}
+			store.PutSSOSession(session)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to save SSO session: %w", err)
+		}
+
+		return &ssoSessionResult{Action: "created", Session: session.Name}, nil
+	}),
+}
+
+var ssoEditCmd = &cobra.Command{
+	Use:   "edit [name]",
+	Short: "Edit an SSO session",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: runResult(func(cmd *cobra.Command, args []string) (any, error) {
+		store, err := loadStore()
+		if err != nil {
+			return nil, err
+		}
+		name, err := selectSSOSession(store, args, "Choose an SSO session to edit:")
+		if err != nil {
+			return nil, err
+		}
+
+		session, ok := store.GetSSOSession(name)
+		if !ok {
+			return nil, output.Errorf(output.CodeNotFound, "SSO session '%s' not found", name)
+		}
+		if users := store.ProfilesUsingSSOSession(name); len(users) > 0 {
+			fmt.Fprintf(os.Stderr, "ℹ️  Used by %d profile(s): %s\n", len(users), strings.Join(users, ", "))
+		}
+
+		if err := askSSOSessionSettings(store, session, false); err != nil {
+			return nil, err
+		}
+
+		err = updateStore(func(store *profiles.Store) error {
+			store.PutSSOSession(session)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to save SSO session: %w", err)
+		}
+
+		return &ssoSessionResult{Action: "updated", Session: name}, nil
+	}),
+}
+
+var ssoRmCascade bool
+
+var ssoRmCmd = &cobra.Command{
+	Use:   "rm [name]",
+	Short: "Remove an SSO session",
+	Long: `Remove an SSO session. If profiles still use the session, gsd refuses
+unless you confirm removing those profiles too (or pass --cascade).`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runResult(func(cmd *cobra.Command, args []string) (any, error) {
+		store, err := loadStore()
+		if err != nil {
+			return nil, err
+		}
+		name, err := selectSSOSession(store, args, "Choose an SSO session to remove:")
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := store.GetSSOSession(name); !ok {
+			return nil, output.Errorf(output.CodeNotFound, "SSO session '%s' not found", name)
+		}
+
+		users := store.ProfilesUsingSSOSession(name)
+		if len(users) > 0 {
+			fmt.Fprintf(os.Stderr, "⚠️  SSO session '%s' is used by: %s\n", name, strings.Join(users, ", "))
+			if !ssoRmCascade {
+				cascade := false
+				err := askOne(&survey.Confirm{
+					Message: fmt.Sprintf("Remove these %d profile(s) as well?", len(users)),
+					Default: false,
+				}, &cascade)
+				if err != nil {
+					return nil, err
+				}
+				if !cascade {
+					return nil, output.Errorf(output.CodeConflict, "SSO session '%s' is still in use", name)
+				}
+			}
+		} else {
+			var confirm bool
+			err := askOne(&survey.Confirm{
+				Message: fmt.Sprintf("⚠️  Are you sure you want to remove SSO session '%s'?", name),
+				Default: false,
+			}, &confirm)
+			if err != nil {
+				return nil, err
+			}
+			if !confirm {
+				return cancelled()
+			}
+		}
+
+		err = updateStore(func(store *profiles.Store) error {
+			for _, profile := range store.ProfilesUsingSSOSession(name) {
+				store.Remove(profile)
+			}
+			store.RemoveSSOSession(name)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to save changes: %w", err)
+		}
+		forgetIdentities(users...)
+
+		return &ssoSessionResult{Action: "removed", Session: name, RemovedProfiles: users}, nil
+	}),
+}
+
+// selectSSOSession returns the session named in args or asks for one.
+func selectSSOSession(store *profiles.Store, args []string, message string) (string, error) {
+	if len(args) == 1 {
+		return args[0], nil
+	}
+	names := store.SSOSessionNames()
+	if len(names) == 0 {
+		return "", output.Errorf(output.CodeNotFound, "no SSO sessions found")
+	}
+	var name string
+	err := askOne(&survey.Select{Message: message, Options: names}, &name)
+	return name, err
+}
+
+func init() {
+	ssoRmCmd.Flags().BoolVar(&ssoRmCascade, "cascade", false, "Also remove profiles that use the session")
+
+	ssoCmd.AddCommand(ssoLsCmd)
+	ssoCmd.AddCommand(ssoAddCmd)
+	ssoCmd.AddCommand(ssoEditCmd)
+	ssoCmd.AddCommand(ssoRmCmd)
+	rootCmd.AddCommand(ssoCmd)
+}
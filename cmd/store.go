@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/aphexlog/gsd/internal/profiles"
+	"github.com/aphexlog/gsd/internal/state"
+)
+
+// awsPaths resolves the config and credentials files from the global flags
+// and the AWS environment variables.
+func awsPaths() profiles.Paths {
+	return profiles.ResolvePaths(configFileFlag, credentialsFileFlag)
+}
+
+// activeProfile returns the profile AWS tools will use: AWS_PROFILE if set,
+// otherwise the last profile chosen with gsd switch, otherwise default.
+func activeProfile() string {
+	if profile := os.Getenv("AWS_PROFILE"); profile != "" {
+		return profile
+	}
+	if profile := state.GlobalProfile(); profile != "" {
+		return profile
+	}
+	return profiles.DefaultProfile
+}
+
+// loadStore reads the AWS config and credentials files.
+func loadStore() (*profiles.Store, error) {
+	store, err := profiles.Load(awsPaths())
+	if err != nil {
+		return nil, fmt.Errorf("unable to load AWS profiles: %w", err)
+	}
+	return store, nil
+}
+
+// updateStore applies fn to freshly loaded AWS files under the gsd lock and
+// saves the result atomically, recording a history snapshot once it is
+// written.
+func updateStore(fn func(*profiles.Store) error) error {
+	return profiles.Update(awsPaths(), func(store *profiles.Store) error {
+		if err := fn(store); err != nil {
+			return err
+		}
+		return saveWithSnapshot(store, "")
+	})
+}
+
+// forgetIdentities drops the cached identities of removed profiles. The
+// profiles are gone already, so a failure is only worth a note.
+func forgetIdentities(names ...string) {
+	for _, name := range names {
+		if err := state.ForgetIdentity(name); err != nil {
+			fmt.Fprintf(os.Stderr, "🤖 Note: Could not update the identity cache: %v\n", err)
+			return
+		}
+	}
+}
+
+// saveWithSnapshot saves the store and then records the files as loaded in
+// the gsd history, so only changes that reached the disk can be undone. It
+// runs inside profiles.Update, whose own Save then has nothing left to write.
+// restores names the snapshot being rolled back to, if any.
+func saveWithSnapshot(store *profiles.Store, restores string) error {
+	changes, err := store.Changes()
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+
+	summary := make([]string, len(changes))
+	for i, change := range changes {
+		summary[i] = change.String()
+	}
+	config, credentials := store.Original()
+	snap := &state.Snapshot{
+		Command:         commandLine,
+		Summary:         summary,
+		Restores:        restores,
+		ConfigPath:      store.Paths.Config,
+		CredentialsPath: store.Paths.Credentials,
+		Config:          string(config),
+		Credentials:     string(credentials),
+	}
+
+	if err := store.Save(); err != nil {
+		return err
+	}
+	// The change is made, so a missing snapshot is no reason to fail it.
+	if err := state.SaveSnapshot(snap); err != nil {
+		fmt.Fprintf(os.Stderr, "🤖 Note: Could not save history snapshot: %v\n", err)
+	}
+	return nil
+}
@@ -2,124 +2,191 @@ package cmd
 
 import (
 	"fmt"
-	"log"
+	"io"
 	"os"
-	"path/filepath"
 	"strings"
 
-	"github.com/AlecAivazis/survey/v2"
+	"github.com/aphexlog/gsd/internal/output"
+	"github.com/aphexlog/gsd/internal/profiles"
+	"github.com/aphexlog/gsd/internal/shell"
+	"github.com/aphexlog/gsd/internal/state"
 	"github.com/spf13/cobra"
-	"gopkg.in/ini.v1"
+)
+
+var (
+	switchGlobal bool
+	switchVerify bool
 )
 
 var switchCmd = &cobra.Command{
-	Use:   "switch",
-	Short: "Switch between AWS profiles interactively",
-	Run: func(cmd *cobra.Command, args []string) {
-		configPath := filepath.Join(os.Getenv("HOME"), ".aws", "config")
-		credPath := filepath.Join(os.Getenv("HOME"), ".aws", "credentials")
-		gsdProfilePath := filepath.Join(os.Getenv("HOME"), ".aws", ".gsd-current")
-
-		// Get available profiles
-		profiles := make([]string, 0)
-		if cfg, err := ini.Load(configPath); err == nil {
-			for _, section := range cfg.Sections() {
-				name := section.Name()
-				if strings.HasPrefix(name, "profile ") {
-					profiles = append(profiles, strings.TrimPrefix(name, "profile "))
-				}
-			}
+	Use:   "switch [profile | -]",
+	Short: "Switch between AWS profiles",
+	Long: `Switch the AWS profile used by the current shell. This needs the shell
+integration from 'gsd shell-init', which exports AWS_PROFILE in the shell
+that ran gsd and leaves other terminals alone.
+
+Without an argument gsd shows a picker, listing the profiles you use most
+first and the rest grouped by --group-by. A profile that isn't an exact name
+is matched against profile names, aliases (the gsd_aliases setting) and
+account IDs. gsd switches straight away on an exact alias or account ID, or
+on the start of just one profile's name; otherwise the picker offers the
+profiles that match.
+'gsd switch -' goes back to the profile this shell used before, like
+'cd -'; with --global, to the previous global switch.
+
+With --check gsd then asks STS who the profile is, as 'gsd whoami' does,
+and if the SSO token behind it has expired offers to log in right away.
+
+With --global the profile is instead copied over [default] in the config
+and credentials files, which affects every shell and tool on the machine.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runResult(func(cmd *cobra.Command, args []string) (any, error) {
+		if !switchGlobal && !shell.Active() {
+			return nil, errNoShellIntegration
 		}
-
-		if len(profiles) == 0 {
-			fmt.Println("🤖 No AWS profiles found in configuration")
-			return
+		if err := validateGroupBy(); err != nil {
+			return nil, err
 		}
 
-		// Get current profile
-		currentProfile := "default"
-		if data, err := os.ReadFile(gsdProfilePath); err == nil {
-			currentProfile = string(data)
+		store, err := loadStore()
+		if err != nil {
+			return nil, err
 		}
-
-		// Create profile selection prompt
-		var selectedProfile string
-		prompt := &survey.Select{
-			Message: "🤖 Select AWS profile:",
-			Options: profiles,
-			Default: currentProfile,
-			Help:    "Choose the AWS profile you want to use",
+		if len(store.Names()) == 0 {
+			return nil, output.Errorf(output.CodeNotFound, "no AWS profiles found in configuration")
 		}
 
-		// Custom styling
-		surveyOpts := survey.WithIcons(func(icons *survey.IconSet) {
-			icons.Question.Text = "🤖"
-			icons.Question.Format = "cyan"
-			icons.SelectFocus.Text = "→"
-			icons.SelectFocus.Format = "cyan"
-		})
-
-		err := survey.AskOne(prompt, &selectedProfile, surveyOpts)
+		currentProfile := activeProfile()
+		selectedProfile, err := resolveSwitchTarget(store, args, currentProfile)
 		if err != nil {
-			if err.Error() == "interrupt" {
-				fmt.Println("\n🤖 Operation cancelled")
-				os.Exit(0)
+			return nil, err
+		}
+
+		if !switchGlobal {
+			region, err := exportProfile(store, selectedProfile, currentProfile)
+			if err != nil {
+				return nil, err
+			}
+			recordSwitch(selectedProfile, false)
+			result := &switchResult{Profile: selectedProfile, Scope: "shell", Region: region}
+			if switchVerify {
+				result.Check = checkSwitch(store, selectedProfile)
 			}
-			log.Fatalf("🤖 Error selecting profile: %v", err)
+			return result, nil
 		}
 
-		// --- CONFIG ---
-		cfg, err := ini.Load(configPath)
+		// --- CONFIG & CREDENTIALS ---
+		err = updateStore(func(store *profiles.Store) error {
+			return store.CopyToDefault(selectedProfile)
+		})
 		if err != nil {
-			log.Fatalf("🤖 Unable to load config: %v", err)
+			return nil, fmt.Errorf("unable to switch profile: %w", err)
 		}
+		recordSwitch(selectedProfile, true)
 
-		srcSectionName := "profile " + selectedProfile
-		if !cfg.HasSection(srcSectionName) {
-			log.Fatalf("🤖 Profile '%s' not found", selectedProfile)
+		result := &switchResult{Profile: selectedProfile, Scope: "global"}
+		if switchVerify {
+			result.Check = checkSwitch(store, selectedProfile)
 		}
+		return result, nil
+	}),
+}
 
-		src := cfg.Section(srcSectionName)
-		dst := cfg.Section("default")
+// resolveSwitchTarget works out the profile to switch to from the
+// argument: "-" for the previous one, a name, or a query to match.
+// Without an argument, or when a query doesn't clearly name one profile,
+// it asks.
+func resolveSwitchTarget(store *profiles.Store, args []string, current string) (string, error) {
+	if len(args) == 0 {
+		if err := requireTerminal("a profile name, e.g. 'gsd switch dev'"); err != nil {
+			return "", err
+		}
+		return askProfile(store, store.Names(), current, "🤖 Select AWS profile:")
+	}
 
-		for _, key := range dst.Keys() {
-			dst.DeleteKey(key.Name())
+	query := args[0]
+	if query == "-" {
+		previous, err := previousProfile(current)
+		if err != nil {
+			return "", err
 		}
-		for _, key := range src.Keys() {
-			dst.Key(key.Name()).SetValue(key.Value())
+		if !store.Has(previous) {
+			return "", output.Errorf(output.CodeNotFound, "previous profile '%s' no longer exists", previous)
 		}
+		return previous, nil
+	}
+	if store.Has(query) {
+		return query, nil
+	}
+
+	candidates, sure := store.Match(query)
+	switch {
+	case len(candidates) == 0:
+		return "", output.Errorf(output.CodeNotFound, "no profile matches '%s'", query)
+	case sure:
+		return candidates[0], nil
+	}
+	// A looser match could be the wrong account, so it is never taken
+	// without asking.
+	if !isTerminal() {
+		return "", output.Errorf(output.CodeInvalidArgument, "'%s' is not a profile name, alias or account ID; it could mean: %s",
+			query, strings.Join(candidates, ", "))
+	}
+	return askProfile(store, candidates, current, "🤖 Select AWS profile:")
+}
 
-		if err := cfg.SaveTo(configPath); err != nil {
-			log.Fatalf("🤖 Unable to save config: %v", err)
+// previousProfile returns the profile `gsd switch -` goes back to: the one
+// this shell used before, or for --global the previous global switch.
+func previousProfile(current string) (string, error) {
+	if switchGlobal {
+		previous, err := state.PreviousProfile(current)
+		if err != nil {
+			return "", output.Errorf(output.CodeNotFound, "%w", err)
 		}
+		return previous, nil
+	}
+	previous := os.Getenv(shell.PreviousVar)
+	if previous == "" {
+		return "", output.Errorf(output.CodeNotFound, "no previous profile to switch back to in this shell")
+	}
+	return previous, nil
+}
 
-		// --- CREDENTIALS ---
-		creds, err := ini.Load(credPath)
-		if err == nil && creds.HasSection(selectedProfile) {
-			srcCred := creds.Section(selectedProfile)
-			dstCred := creds.Section("default")
-
-			for _, key := range dstCred.Keys() {
-				dstCred.DeleteKey(key.Name())
-			}
-			for _, key := range srcCred.Keys() {
-				dstCred.Key(key.Name()).SetValue(key.Value())
-			}
+// recordSwitch adds a switch to the history `gsd switch -` reads. Failing
+// to do so doesn't undo the switch.
+func recordSwitch(profile string, global bool) {
+	if err := state.RecordSwitch(profile, global); err != nil {
+		fmt.Fprintf(os.Stderr, "🤖 Note: Could not save switch history: %v\n", err)
+	}
+}
 
-			if err := creds.SaveTo(credPath); err != nil {
-				log.Fatalf("🤖 Unable to save credentials: %v", err)
-			}
-		}
+// switchResult is the result of `gsd switch`.
+// Scope is "shell" when only the calling shell's AWS_PROFILE changed and
+// "global" when [default] was rewritten.
+type switchResult struct {
+	Profile string `json:"profile" yaml:"profile"`
+	Scope   string `json:"scope" yaml:"scope"`
+	Region  string `json:"region,omitempty" yaml:"region,omitempty"`
 
-		// --- TRACK CURRENT PROFILE ---
-		if err := os.WriteFile(gsdProfilePath, []byte(selectedProfile), 0600); err != nil {
-			log.Printf("🤖 Note: Could not save current profile: %v", err)
-		}
+	// Check is the credential check from --check.
+	Check *switchCheck `json:"check,omitempty" yaml:"check,omitempty"`
+}
 
-		fmt.Printf("🤖 Switched to profile: '%s'\n", selectedProfile)
-	},
+func (r *switchResult) Text(w io.Writer) {
+	if r.Scope == "global" {
+		fmt.Fprintf(w, "🤖 Switched to profile: '%s' (all shells)\n", r.Profile)
+	} else {
+		fmt.Fprintf(w, "🤖 Switched to profile: '%s'\n", r.Profile)
+	}
+	if r.Check != nil {
+		r.Check.Text(w)
+	}
 }
 
 func init() {
+	switchCmd.Flags().BoolVar(&switchGlobal, "global", false, "Copy the profile over [default] for every shell instead")
+	switchCmd.Flags().BoolVar(&switchVerify, "check", defaultSwitchCheck(), "Verify the credentials with STS afterwards and offer to log in if the SSO token expired; GSD_SWITCH_CHECK=1 turns it on by default")
+	switchCmd.Flags().StringVar(&pickerGroupBy, "group-by", defaultGroupBy(), "Group the picker by session, account, tag or none; GSD_GROUP_BY sets the default")
+
 	rootCmd.AddCommand(switchCmd)
 }
@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/aphexlog/gsd/internal/profiles"
+	"github.com/aphexlog/gsd/internal/ssocache"
+	"github.com/aphexlog/gsd/internal/state"
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// switchCheckTimeout bounds the identity check after a switch, so an
+// unreachable endpoint doesn't hold up the shell for long.
+const switchCheckTimeout = 5 * time.Second
+
+// Outcomes of the check after a switch.
+const (
+	identityValid   = "valid"
+	identityExpired = "expired"
+	identityFailed  = "failed"
+)
+
+// switchCheck is the outcome of checking a profile's credentials with STS
+// right after switching to it.
+type switchCheck struct {
+	Status       string `json:"status" yaml:"status"`
+	Account      string `json:"account,omitempty" yaml:"account,omitempty"`
+	AccountAlias string `json:"account_alias,omitempty" yaml:"account_alias,omitempty"`
+	ARN          string `json:"arn,omitempty" yaml:"arn,omitempty"`
+	Error        string `json:"error,omitempty" yaml:"error,omitempty"`
+
+	// LoginProfile is the SSO profile to log in with when the token has
+	// expired, and LoggedIn whether gsd just did.
+	LoginProfile string `json:"login_profile,omitempty" yaml:"login_profile,omitempty"`
+	LoggedIn     bool   `json:"logged_in,omitempty" yaml:"logged_in,omitempty"`
+}
+
+// defaultSwitchCheck turns --check on for every switch when GSD_SWITCH_CHECK
+// is 1.
+func defaultSwitchCheck() bool {
+	return os.Getenv("GSD_SWITCH_CHECK") == "1"
+}
+
+// checkSwitch runs the whoami identity check for a profile. If it fails
+// because the SSO token behind the profile has expired, it offers to log
+// in and checks again.
+func checkSwitch(store *profiles.Store, profile string) *switchCheck {
+	check := probeIdentity(profile)
+	if check.Status == identityValid {
+		return check
+	}
+
+	login := ssoLoginProfile(store, profile)
+	if login == nil {
+		return check
+	}
+	if token, err := ssocache.LoadForProfile(login); err == nil && token.Valid() {
+		// The token is fine, so the failure is something login won't fix.
+		return check
+	}
+	check = &switchCheck{Status: identityExpired, LoginProfile: login.Name}
+	if !isTerminal() {
+		return check
+	}
+
+	confirm := true
+	prompt := &survey.Confirm{
+		Message: fmt.Sprintf("🔐 The SSO token for '%s' has expired. Log in now?", profile),
+		Default: true,
+	}
+	if err := askOne(prompt, &confirm); err != nil || !confirm {
+		return check
+	}
+	if err := ssoLogin(login.Name); err != nil {
+		check.Error = err.Error()
+		return check
+	}
+
+	check = probeIdentity(profile)
+	check.LoginProfile = login.Name
+	check.LoggedIn = true
+	return check
+}
+
+// probeIdentity asks STS who the profile is, within switchCheckTimeout.
+func probeIdentity(profile string) *switchCheck {
+	ctx, cancel := context.WithTimeout(context.Background(), switchCheckTimeout)
+	defer cancel()
+
+	identity, err := callerIdentity(ctx, profile)
+	if err != nil {
+		return &switchCheck{Status: identityFailed, Error: probeError(err)}
+	}
+	rememberIdentity(profile, identity)
+	account := aws.ToString(identity.Account)
+	return &switchCheck{
+		Status:       identityValid,
+		Account:      account,
+		AccountAlias: state.AccountAlias(account),
+		ARN:          aws.ToString(identity.Arn),
+	}
+}
+
+// ssoLoginProfile returns the SSO profile whose login provides the
+// credentials of profile: the profile itself, or the end of its
+// source_profile chain. It returns nil for profiles that don't use SSO.
+func ssoLoginProfile(store *profiles.Store, profile string) *profiles.Profile {
+	chain, err := store.RoleChain(profile)
+	if err != nil {
+		return nil
+	}
+	root, _ := store.Get(chain[len(chain)-1])
+	if root.Type != profiles.TypeSSO {
+		return nil
+	}
+	return root
+}
+
+func (c *switchCheck) Text(w io.Writer) {
+	switch c.Status {
+	case identityValid:
+		if c.LoggedIn {
+			fmt.Fprintln(w, "✅ Login successful.")
+		}
+		if c.AccountAlias != "" {
+			fmt.Fprintf(w, "🪪 Account: %s (%s)\n", c.Account, c.AccountAlias)
+		} else {
+			fmt.Fprintf(w, "🪪 Account: %s\n", c.Account)
+		}
+		fmt.Fprintf(w, "👤 ARN:     %s\n", c.ARN)
+	case identityExpired:
+		if c.Error != "" {
+			fmt.Fprintf(w, "⚠️  %s\n", c.Error)
+		}
+		fmt.Fprintf(w, "⚠️  The SSO token has expired; run 'aws sso login --profile %s'\n", c.LoginProfile)
+	default:
+		fmt.Fprintf(w, "⚠️  Could not verify credentials: %s\n", c.Error)
+	}
+}
@@ -0,0 +1,276 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/aphexlog/gsd/internal/manifest"
+	"github.com/aphexlog/gsd/internal/output"
+	"github.com/aphexlog/gsd/internal/profiles"
+	"github.com/spf13/cobra"
+)
+
+var (
+	syncManifest string
+	syncPrune    bool
+	syncAdopt    bool
+	syncDryRun   bool
+	syncYes      bool
+)
+
+var configSyncCmd = &cobra.Command{
+	Use:   "sync --manifest <file>",
+	Short: "Reconcile profiles with a team manifest",
+	Long: `Create and update the SSO sessions and profiles described by a team
+manifest. Profiles gsd creates are tagged with the manifest name
+(gsd_managed_by), and only tagged profiles are ever changed or removed, so
+personal profiles are left alone. The plan is printed before anything is
+written.
+
+  gsd config sync --manifest team/aws.yaml --dry-run
+  gsd config sync --manifest team/aws.yaml --prune`,
+	Args: cobra.NoArgs,
+	RunE: runResult(func(cmd *cobra.Command, args []string) (any, error) {
+		if syncManifest == "" {
+			return nil, output.Errorf(output.CodeInvalidArgument, "--manifest is required")
+		}
+		m, err := manifest.Load(syncManifest)
+		if err != nil {
+			return nil, output.Errorf(output.CodeInvalidArgument, "invalid manifest: %w", err)
+		}
+		store, err := loadStore()
+		if err != nil {
+			return nil, err
+		}
+
+		plan := planSync(store, m)
+		if syncDryRun || !plan.hasChanges() {
+			return plan, nil
+		}
+
+		if !syncYes {
+			plan.Text(os.Stderr)
+			var confirm bool
+			if err := askFlag("--yes", &survey.Confirm{Message: "Apply these changes?"}, &confirm); err != nil {
+				return nil, err
+			}
+			if !confirm {
+				return cancelled()
+			}
+		}
+
+		confirmed := plan
+		if syncYes {
+			confirmed = nil
+		}
+		return applySync(m, confirmed)
+	}),
+}
+
+// applySync plans the sync again under the lock, so nothing written since
+// the plan was made is overwritten, and applies it. If confirmed is the plan
+// the user agreed to, a different plan is refused rather than applied.
+func applySync(m *manifest.Manifest, confirmed *syncResult) (*syncResult, error) {
+	var plan *syncResult
+	err := updateStore(func(store *profiles.Store) error {
+		plan = planSync(store, m)
+		if confirmed != nil && !plan.sameAs(confirmed) {
+			return output.Errorf(output.CodeConflict, "the AWS files changed after the plan was made; run the sync again")
+		}
+		for _, item := range plan.Items {
+			switch item.Action {
+			case syncAdd, syncUpdate:
+				store.Import(item.Kind, *item.section)
+			case syncRemove:
+				if item.Kind == profiles.SSOSessionSection {
+					store.RemoveSSOSession(item.Name)
+				} else {
+					store.Remove(item.Name)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to sync profiles: %w", err)
+	}
+	for _, item := range plan.Items {
+		if item.Action == syncRemove && item.Kind == profiles.ProfileSection {
+			forgetIdentities(item.Name)
+		}
+	}
+	plan.Applied = true
+	return plan, nil
+}
+
+// Sync plan actions.
+const (
+	syncAdd      = "add"
+	syncUpdate   = "update"
+	syncRemove   = "remove"
+	syncConflict = "conflict"
+	syncOrphan   = "orphan"
+)
+
+// planSync compares the manifest with the store. Sections tagged with the
+// manifest's name are updated or pruned; anything else with a clashing name
+// is a conflict unless --adopt is given.
+func planSync(store *profiles.Store, m *manifest.Manifest) *syncResult {
+	plan := &syncResult{Manifest: m.Name, DryRun: syncDryRun}
+
+	desired := []struct {
+		kind     profiles.SectionKind
+		sections []profiles.BundleSection
+	}{
+		{profiles.SSOSessionSection, m.SessionSections()},
+		{profiles.ProfileSection, m.Profiles()},
+	}
+
+	wanted := make(map[profiles.Target]bool)
+	for _, group := range desired {
+		for i := range group.sections {
+			section := &group.sections[i]
+			target := profiles.Target{Kind: group.kind, Name: section.Name}
+			wanted[target] = true
+			// Aliases and tags the user added are theirs to keep.
+			section.Settings = store.KeepUserMetadata(target, section.Settings)
+
+			status, diff := store.CompareImport(group.kind, *section)
+			item := syncItem{Kind: group.kind, Name: section.Name, Diff: diff, section: section}
+			switch {
+			case status == profiles.ImportUnchanged:
+				plan.Unchanged++
+				continue
+			case status == profiles.ImportNew:
+				item.Action = syncAdd
+			case store.ManagedBy(target) == m.Name || syncAdopt:
+				item.Action = syncUpdate
+			default:
+				item.Action = syncConflict
+				item.Reason = fmt.Sprintf("exists and is not managed by '%s' (use --adopt to take it over)", m.Name)
+			}
+			plan.Items = append(plan.Items, item)
+		}
+	}
+
+	// Profiles this manifest created that it no longer describes.
+	removed := make(map[string]bool)
+	for _, name := range store.Names() {
+		target := profiles.Target{Kind: profiles.ProfileSection, Name: name}
+		if wanted[target] || store.ManagedBy(target) != m.Name {
+			continue
+		}
+		item := syncItem{Kind: profiles.ProfileSection, Name: name, Action: syncOrphan, Reason: "no longer in the manifest (use --prune to remove it)"}
+		if syncPrune {
+			item.Action, item.Reason = syncRemove, ""
+			removed[name] = true
+		}
+		plan.Items = append(plan.Items, item)
+	}
+
+	// Sessions are only pruned once nothing uses them any more.
+	for _, name := range store.SSOSessionNames() {
+		target := profiles.Target{Kind: profiles.SSOSessionSection, Name: name}
+		if wanted[target] || store.ManagedBy(target) != m.Name {
+			continue
+		}
+		inUse := false
+		for _, user := range store.ProfilesUsingSSOSession(name) {
+			if !removed[user] {
+				inUse = true
+			}
+		}
+		item := syncItem{Kind: profiles.SSOSessionSection, Name: name, Action: syncOrphan, Reason: "no longer in the manifest (use --prune to remove it)"}
+		if inUse {
+			item.Reason = "no longer in the manifest but still used by other profiles"
+		} else if syncPrune {
+			item.Action, item.Reason = syncRemove, ""
+		}
+		plan.Items = append(plan.Items, item)
+	}
+	return plan
+}
+
+// syncItem is one planned change.
+type syncItem struct {
+	Kind   profiles.SectionKind `json:"kind" yaml:"kind"`
+	Name   string               `json:"name" yaml:"name"`
+	Action string               `json:"action" yaml:"action"`
+	Reason string               `json:"reason,omitempty" yaml:"reason,omitempty"`
+	Diff   []string             `json:"diff,omitempty" yaml:"diff,omitempty"`
+
+	section *profiles.BundleSection
+}
+
+// syncResult is the plan, and after applying it the result, of
+// `gsd config sync`.
+type syncResult struct {
+	Manifest  string     `json:"manifest" yaml:"manifest"`
+	DryRun    bool       `json:"dry_run" yaml:"dry_run"`
+	Applied   bool       `json:"applied" yaml:"applied"`
+	Items     []syncItem `json:"items" yaml:"items"`
+	Unchanged int        `json:"unchanged" yaml:"unchanged"`
+}
+
+func (r *syncResult) count(action string) int {
+	n := 0
+	for _, item := range r.Items {
+		if item.Action == action {
+			n++
+		}
+	}
+	return n
+}
+
+// sameAs reports whether two plans make the same changes.
+func (r *syncResult) sameAs(other *syncResult) bool {
+	return slices.EqualFunc(r.Items, other.Items, func(a, b syncItem) bool {
+		return a.Kind == b.Kind && a.Name == b.Name && a.Action == b.Action && slices.Equal(a.Diff, b.Diff)
+	})
+}
+
+func (r *syncResult) hasChanges() bool {
+	return r.count(syncAdd)+r.count(syncUpdate)+r.count(syncRemove) > 0
+}
+
+func (r *syncResult) Text(w io.Writer) {
+	if r.Applied {
+		fmt.Fprintf(w, "✨ Synced manifest '%s': %d added, %d changed, %d removed\n",
+			r.Manifest, r.count(syncAdd), r.count(syncUpdate), r.count(syncRemove))
+		return
+	}
+	if len(r.Items) == 0 {
+		fmt.Fprintf(w, "✨ Everything matches manifest '%s' (%d unchanged)\n", r.Manifest, r.Unchanged)
+		return
+	}
+
+	symbols := map[string]string{syncAdd: "+", syncUpdate: "~", syncRemove: "-", syncConflict: "!", syncOrphan: "?"}
+	fmt.Fprintf(w, "📋 Sync plan for manifest '%s':\n", r.Manifest)
+	for _, item := range r.Items {
+		if item.Reason != "" {
+			fmt.Fprintf(w, "  %s %s %s: %s\n", symbols[item.Action], item.Kind, item.Name, item.Reason)
+			continue
+		}
+		fmt.Fprintf(w, "  %s %s %s\n", symbols[item.Action], item.Kind, item.Name)
+		if item.Action != syncRemove {
+			for _, line := range item.Diff {
+				fmt.Fprintf(w, "      %s\n", line)
+			}
+		}
+	}
+	fmt.Fprintf(w, "\nPlan: %d to add, %d to change, %d to remove, %d unchanged.\n",
+		r.count(syncAdd), r.count(syncUpdate), r.count(syncRemove), r.Unchanged)
+}
+
+func init() {
+	configSyncCmd.Flags().StringVar(&syncManifest, "manifest", "", "Path to the team manifest (YAML)")
+	configSyncCmd.Flags().BoolVar(&syncPrune, "prune", false, "Remove managed profiles that are no longer in the manifest")
+	configSyncCmd.Flags().BoolVar(&syncAdopt, "adopt", false, "Take over existing unmanaged profiles whose names clash")
+	configSyncCmd.Flags().BoolVar(&syncDryRun, "dry-run", false, "Print the plan without changing anything")
+	configSyncCmd.Flags().BoolVarP(&syncYes, "yes", "y", false, "Apply the plan without asking")
+
+	configCmd.AddCommand(configSyncCmd)
+}
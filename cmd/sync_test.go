@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aphexlog/gsd/internal/manifest"
+	"github.com/aphexlog/gsd/internal/profiles"
+)
+
+const syncConfig = `[sso-session corp]
+sso_start_url = https://corp.awsapps.com/start
+sso_region = us-east-1
+sso_registration_scopes = sso:account:access
+gsd_managed_by = team
+
+[profile sandbox-Admin]
+sso_session = corp
+sso_account_id = 111111111111
+sso_role_name = Admin
+gsd_managed_by = team
+`
+
+// loadTestStore writes config to a temporary directory and loads it.
+func loadTestStore(t *testing.T, config string) *profiles.Store {
+	t.Helper()
+	dir := t.TempDir()
+	paths := profiles.Paths{
+		Config:      filepath.Join(dir, "config"),
+		Credentials: filepath.Join(dir, "credentials"),
+	}
+	if err := os.WriteFile(paths.Config, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+	store, err := profiles.Load(paths)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+// testManifest manages sso-session corp and no profiles, so every profile
+// it tagged is an orphan.
+func testManifest() *manifest.Manifest {
+	return &manifest.Manifest{
+		Version:     manifest.Version,
+		Name:        "team",
+		SSOSessions: []manifest.SSOSession{{Name: "corp", StartURL: "https://corp.awsapps.com/start", Region: "us-east-1"}},
+	}
+}
+
+func TestSyncPruneKeepsClones(t *testing.T) {
+	store := loadTestStore(t, syncConfig)
+	if err := store.Clone("sandbox-Admin", "my-sandbox"); err != nil {
+		t.Fatal(err)
+	}
+
+	syncPrune = true
+	defer func() { syncPrune = false }()
+
+	plan := planSync(store, testManifest())
+	for _, item := range plan.Items {
+		if item.Name == "my-sandbox" {
+			t.Errorf("clone is in the plan: %+v", item)
+		}
+	}
+	if got := plan.count(syncRemove); got != 1 {
+		t.Errorf("%d removals, want 1: %+v", got, plan.Items)
+	}
+}
+
+func TestSyncUpdateKeepsUserMetadata(t *testing.T) {
+	store := loadTestStore(t, syncConfig+"gsd_aliases = sb\ngsd_tags = dev\n")
+	m := testManifest()
+	m.Accounts = []manifest.Account{{Name: "sandbox", ID: "111111111111", Region: "eu-west-1"}}
+	m.Roles = []manifest.Role{{Name: "Admin"}}
+
+	plan := planSync(store, m)
+	if got := plan.count(syncUpdate); got != 1 {
+		t.Fatalf("%d updates, want 1: %+v", got, plan.Items)
+	}
+	for _, line := range plan.Items[0].Diff {
+		if line[0] == '-' {
+			t.Errorf("update removes a key: %s", line)
+		}
+	}
+
+	store.Import(plan.Items[0].Kind, *plan.Items[0].section)
+	p, _ := store.Get("sandbox-Admin")
+	if p.Region != "eu-west-1" || len(p.Aliases) != 1 || len(p.Tags) != 1 {
+		t.Errorf("after sync: region %q, aliases %v, tags %v", p.Region, p.Aliases, p.Tags)
+	}
+}
+
+func TestApplySyncReplans(t *testing.T) {
+	m := testManifest()
+	m.Accounts = []manifest.Account{{Name: "sandbox", ID: "111111111111", Region: "eu-west-1"}}
+	m.Roles = []manifest.Role{{Name: "Admin"}}
+
+	cases := []struct {
+		name      string
+		edit      string
+		confirmed bool
+		wantErr   bool
+	}{
+		{"unrelated edit is kept", "\n[profile personal]\nregion = us-east-2\n", false, false},
+		{"unrelated edit is kept after confirming", "\n[profile personal]\nregion = us-east-2\n", true, false},
+		{"confirmed plan went stale", "region = ap-south-1\n", true, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			paths := useTestFiles(t, syncConfig)
+			store, err := profiles.Load(paths)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			plan := planSync(store, m)
+			if err := os.WriteFile(paths.Config, []byte(syncConfig+tc.edit), 0600); err != nil {
+				t.Fatal(err)
+			}
+			var confirmed *syncResult
+			if tc.confirmed {
+				confirmed = plan
+			}
+
+			_, err = applySync(m, confirmed)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected the stale plan to be refused")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			after, err := profiles.Load(paths)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !after.Has("personal") {
+				t.Error("sync overwrote a profile added after planning")
+			}
+			if p, _ := after.Get("sandbox-Admin"); p.Region != "eu-west-1" {
+				t.Errorf("sandbox-Admin region = %q, want eu-west-1", p.Region)
+			}
+		})
+	}
+}
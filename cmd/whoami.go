@@ -3,55 +3,136 @@ package cmd
 import (
 	"context"
 	"fmt"
-	"os"
-	"path/filepath"
-	"strings"
+	"io"
+	"time"
 
+	"github.com/aphexlog/gsd/internal/output"
+	"github.com/aphexlog/gsd/internal/state"
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"github.com/spf13/cobra"
 )
 
+var whoamiAlias bool
+
 // whoamiCmd represents the whoami command
 var whoamiCmd = &cobra.Command{
 	Use:   "whoami",
 	Short: "Prints the current AWS profile and identity",
-	Long:  `Resolves the current AWS profile and uses STS to show the active account and identity.`,
-	Run: func(cmd *cobra.Command, args []string) {
+	Long: `Resolves the current AWS profile and uses STS to show the active account and identity.
+
+The account alias shown is the one gsd has cached, from 'config discover' or
+an earlier --alias. --alias looks it up with IAM and caches it for 'gsd prompt'.`,
+	RunE: runResult(func(cmd *cobra.Command, args []string) (any, error) {
 		ctx := context.TODO()
 
 		// Determine active profile
-		profile := os.Getenv("AWS_PROFILE")
-		if profile == "" {
-			gsdPath := filepath.Join(os.Getenv("HOME"), ".aws", ".gsd-current")
-			data, err := os.ReadFile(gsdPath)
-			if err == nil {
-				profile = strings.TrimSpace(string(data))
-			} else {
-				profile = "default"
-			}
-		}
+		profile := activeProfile()
 
-		cfg, err := config.LoadDefaultConfig(ctx, config.WithSharedConfigProfile(profile))
+		identity, err := callerIdentity(ctx, profile)
 		if err != nil {
-			fmt.Printf("❌ Failed to load AWS config: %v\n", err)
-			return
+			return nil, err
 		}
 
-		stsClient := sts.NewFromConfig(cfg)
-		identity, err := stsClient.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
-		if err != nil {
-			fmt.Printf("❌ Failed to get identity: %v\n", err)
-			return
+		result := &whoamiResult{
+			Profile: profile,
+			Account: aws.ToString(identity.Account),
+			ARN:     aws.ToString(identity.Arn),
+			UserID:  aws.ToString(identity.UserId),
+		}
+		rememberIdentity(profile, identity)
+		if whoamiAlias {
+			result.AccountAlias = lookupAccountAlias(ctx, profile, result.Account)
+		} else {
+			result.AccountAlias = state.AccountAlias(result.Account)
 		}
+		return result, nil
+	}),
+}
+
+// loadAWSConfig loads the SDK configuration for a profile from the files
+// gsd is using.
+func loadAWSConfig(ctx context.Context, profile string) (aws.Config, error) {
+	paths := awsPaths()
+	cfg, err := config.LoadDefaultConfig(ctx,
+		config.WithSharedConfigProfile(profile),
+		config.WithSharedConfigFiles([]string{paths.Config}),
+		config.WithSharedCredentialsFiles([]string{paths.Credentials}),
+	)
+	if err != nil {
+		return cfg, output.Errorf(output.CodeAWS, "failed to load AWS config: %w", err)
+	}
+	return cfg, nil
+}
 
-		fmt.Printf("🧠 Profile: %s\n", profile)
-		fmt.Printf("🪪 Account: %s\n", *identity.Account)
-		fmt.Printf("👤 ARN:     %s\n", *identity.Arn)
-		fmt.Printf("🆔 User ID: %s\n", *identity.UserId)
-	},
+// callerIdentity asks STS who a profile's credentials belong to.
+func callerIdentity(ctx context.Context, profile string) (*sts.GetCallerIdentityOutput, error) {
+	cfg, err := loadAWSConfig(ctx, profile)
+	if err != nil {
+		return nil, err
+	}
+	identity, err := sts.NewFromConfig(cfg).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return nil, output.Errorf(output.CodeAWS, "failed to get identity: %w", err)
+	}
+	return identity, nil
+}
+
+// aliasTimeout bounds the account alias lookup of --alias.
+const aliasTimeout = 3 * time.Second
+
+// rememberIdentity caches a profile's identity for `gsd prompt`. Failures
+// are ignored, as the cache is only a convenience.
+func rememberIdentity(profile string, identity *sts.GetCallerIdentityOutput) {
+	_ = state.CacheIdentity(profile, state.Identity{
+		Account: aws.ToString(identity.Account),
+		ARN:     aws.ToString(identity.Arn),
+	})
+}
+
+// lookupAccountAlias asks IAM for the account alias and caches it. It falls
+// back to the cached name when the credentials may not read the alias.
+func lookupAccountAlias(ctx context.Context, profile, account string) string {
+	ctx, cancel := context.WithTimeout(ctx, aliasTimeout)
+	defer cancel()
+
+	cfg, err := loadAWSConfig(ctx, profile)
+	if err != nil {
+		return state.AccountAlias(account)
+	}
+	out, err := iam.NewFromConfig(cfg).ListAccountAliases(ctx, &iam.ListAccountAliasesInput{})
+	if err != nil || len(out.AccountAliases) == 0 {
+		return state.AccountAlias(account)
+	}
+	alias := out.AccountAliases[0]
+	_ = state.CacheAccountAlias(account, alias)
+	return alias
+}
+
+// whoamiResult is the caller identity of the active profile.
+type whoamiResult struct {
+	Profile      string `json:"profile" yaml:"profile"`
+	Account      string `json:"account" yaml:"account"`
+	AccountAlias string `json:"account_alias,omitempty" yaml:"account_alias,omitempty"`
+	ARN          string `json:"arn" yaml:"arn"`
+	UserID       string `json:"user_id" yaml:"user_id"`
+}
+
+func (r *whoamiResult) Text(w io.Writer) {
+	fmt.Fprintf(w, "🧠 Profile: %s\n", r.Profile)
+	if r.AccountAlias != "" {
+		fmt.Fprintf(w, "🪪 Account: %s (%s)\n", r.Account, r.AccountAlias)
+	} else {
+		fmt.Fprintf(w, "🪪 Account: %s\n", r.Account)
+	}
+	fmt.Fprintf(w, "👤 ARN:     %s\n", r.ARN)
+	fmt.Fprintf(w, "🆔 User ID: %s\n", r.UserID)
 }
 
 func init() {
+	whoamiCmd.Flags().BoolVar(&whoamiAlias, "alias", false, "Look up the account alias with IAM")
+
 	rootCmd.AddCommand(whoamiCmd)
 }
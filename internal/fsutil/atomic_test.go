@@ -0,0 +1,88 @@
+package fsutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	cases := []struct {
+		name string
+		// setup prepares dir and returns the path to write to.
+		setup func(t *testing.T, dir string) string
+		// file is where the data must end up, relative to dir.
+		file string
+		mode os.FileMode
+	}{
+		{"new file gets perm", func(t *testing.T, dir string) string {
+			return filepath.Join(dir, "config")
+		}, "config", 0600},
+		{"existing file keeps its mode", func(t *testing.T, dir string) string {
+			path := filepath.Join(dir, "config")
+			writeFile(t, path, "old", 0640)
+			return path
+		}, "config", 0640},
+		{"missing directory is created", func(t *testing.T, dir string) string {
+			return filepath.Join(dir, "aws", "config")
+		}, "aws/config", 0600},
+		{"symlink target is replaced", func(t *testing.T, dir string) string {
+			target := filepath.Join(dir, "dotfiles", "config")
+			writeFile(t, target, "old", 0644)
+			link := filepath.Join(dir, "config")
+			if err := os.Symlink(target, link); err != nil {
+				t.Skip("symlinks not supported:", err)
+			}
+			return link
+		}, "dotfiles/config", 0644},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := tc.setup(t, dir)
+			if err := WriteFileAtomic(path, []byte("new"), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			file := filepath.Join(dir, tc.file)
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != "new" {
+				t.Errorf("%s holds %q", tc.file, data)
+			}
+			info, err := os.Stat(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := info.Mode().Perm(); got != tc.mode {
+				t.Errorf("mode = %v, want %v", got, tc.mode)
+			}
+			if fi, err := os.Lstat(path); err != nil {
+				t.Fatal(err)
+			} else if path != file && fi.Mode()&os.ModeSymlink == 0 {
+				t.Error("the symlink was replaced by a file")
+			}
+
+			temps, _ := filepath.Glob(filepath.Join(filepath.Dir(file), ".*.tmp-*"))
+			if len(temps) > 0 {
+				t.Errorf("left behind %v", temps)
+			}
+		})
+	}
+}
+
+func writeFile(t *testing.T, path, data string, mode os.FileMode) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(data), mode); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		t.Fatal(err)
+	}
+}
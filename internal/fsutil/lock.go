@@ -0,0 +1,56 @@
+package fsutil
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// LockTimeout is how long Lock waits for another process to release a lock.
+var LockTimeout = 10 * time.Second
+
+// Lock is an advisory lock held on a lock file.
+type Lock struct {
+	file *os.File
+}
+
+// AcquireLock takes an exclusive advisory lock on path, creating the lock
+// file if needed. It retries until LockTimeout elapses.
+func AcquireLock(path string) (*Lock, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(LockTimeout)
+	for {
+		locked, err := tryLock(f)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		if locked {
+			return &Lock{file: f}, nil
+		}
+		if time.Now().After(deadline) {
+			f.Close()
+			return nil, fmt.Errorf("timed out waiting for lock on %s; is another gsd command running?", path)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// Release drops the lock.
+func (l *Lock) Release() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+	unlock(l.file)
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
@@ -0,0 +1,536 @@
+// Package inifile is a format-preserving editor for the AWS config and
+// credentials files. Unlike a generic INI library it keeps every comment,
+// blank line, indentation choice and unknown section exactly as written, so
+// a change only touches the lines it needs to.
+package inifile
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+type lineKind int
+
+const (
+	lineBlank lineKind = iota
+	lineComment
+	lineKey
+)
+
+// line is one logical line in a section body. Key lines carry any indented
+// sub-property lines that follow them, such as the settings under `s3 =`.
+type line struct {
+	kind  lineKind
+	raw   string // original text; empty for lines added by gsd
+	key   string
+	value string
+	sub   []string
+}
+
+func (l *line) text() string {
+	if l.raw != "" || l.kind != lineKey {
+		return l.raw
+	}
+	if l.value == "" {
+		return l.key + " ="
+	}
+	return l.key + " = " + l.value
+}
+
+// Section is a [header] and the lines that belong to it. Comments directly
+// above a header (with no blank line in between) belong to that section.
+type Section struct {
+	name    string
+	header  string
+	leading []string
+	lines   []*line
+}
+
+// File is a parsed config or credentials file.
+type File struct {
+	root     *Section // keys and comments before the first header
+	sections []*Section
+	eol      string
+	finalEOL bool
+}
+
+// Parse reads data into a File. Bytes on the result reproduces data exactly.
+func Parse(data []byte) (*File, error) {
+	f := &File{root: &Section{}, eol: "\n", finalEOL: true}
+	if bytes.Contains(data, []byte("\r\n")) {
+		f.eol = "\r\n"
+	}
+	if len(data) == 0 {
+		return f, nil
+	}
+
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	f.finalEOL = strings.HasSuffix(text, "\n")
+	text = strings.TrimSuffix(text, "\n")
+
+	current := f.root
+	for i, raw := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(raw)
+		indented := trimmed != "" && raw[0] != trimmed[0]
+
+		switch {
+		case trimmed == "":
+			current.lines = append(current.lines, &line{kind: lineBlank, raw: raw})
+
+		case (trimmed[0] == '#' || trimmed[0] == ';') && !(indented && inBlock(current)):
+			current.lines = append(current.lines, &line{kind: lineComment, raw: raw})
+
+		case indented && lastKey(current) != nil:
+			// Sub-property of the preceding key, e.g. `  max_concurrent_requests = 20`,
+			// or a comment between them.
+			prev := lastKey(current)
+			prev.sub = append(prev.sub, raw)
+
+		case trimmed[0] == '[':
+			end := strings.IndexByte(trimmed, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated section header", i+1)
+			}
+			if rest := strings.TrimSpace(trimmed[end+1:]); rest != "" && rest[0] != '#' && rest[0] != ';' {
+				return nil, fmt.Errorf("line %d: unexpected text after section header", i+1)
+			}
+			section := &Section{
+				name:    normalizeName(trimmed[1:end]),
+				header:  raw,
+				leading: takeLeadingComments(current),
+			}
+			f.sections = append(f.sections, section)
+			current = section
+
+		default:
+			key, value, ok := splitKeyValue(trimmed)
+			if !ok {
+				return nil, fmt.Errorf("line %d: expected 'key = value', got %q", i+1, trimmed)
+			}
+			current.lines = append(current.lines, &line{kind: lineKey, raw: raw, key: key, value: value})
+		}
+	}
+	return f, nil
+}
+
+// lastKey returns the section's final line if it is a key, so indented lines
+// can attach to it. A blank line or an unindented comment in between ends
+// the key.
+func lastKey(s *Section) *line {
+	if len(s.lines) == 0 {
+		return nil
+	}
+	if l := s.lines[len(s.lines)-1]; l.kind == lineKey {
+		return l
+	}
+	return nil
+}
+
+// inBlock reports whether the section ends in a key with nested settings,
+// such as `s3 =`, so an indented comment belongs to it.
+func inBlock(s *Section) bool {
+	l := lastKey(s)
+	return l != nil && (l.value == "" || len(l.sub) > 0)
+}
+
+// takeLeadingComments moves the comment lines at the end of s into the
+// section that follows them.
+func takeLeadingComments(s *Section) []string {
+	i := len(s.lines)
+	for i > 0 && s.lines[i-1].kind == lineComment {
+		i--
+	}
+	var leading []string
+	for _, l := range s.lines[i:] {
+		leading = append(leading, l.raw)
+	}
+	s.lines = s.lines[:i]
+	return leading
+}
+
+func splitKeyValue(s string) (key, value string, ok bool) {
+	i := strings.IndexAny(s, "=:")
+	if i <= 0 {
+		return "", "", false
+	}
+	value = s[i+1:]
+	if c := inlineComment(value); c >= 0 {
+		value = value[:c]
+	}
+	return strings.TrimSpace(s[:i]), strings.TrimSpace(value), true
+}
+
+// inlineComment returns where a trailing `# comment` in a value starts,
+// including the whitespace before it, or -1. The # only counts at the start
+// or after whitespace, so URLs with a fragment are left whole. A ; is not a
+// marker here: credential_process commands use it as a separator.
+func inlineComment(value string) int {
+	for i := 0; i < len(value); i++ {
+		if value[i] != '#' {
+			continue
+		}
+		if i == 0 || value[i-1] == ' ' || value[i-1] == '\t' {
+			return len(strings.TrimRight(value[:i], " \t"))
+		}
+	}
+	return -1
+}
+
+func normalizeName(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// Bytes renders the file.
+func (f *File) Bytes() []byte {
+	var out []string
+	out = appendLines(out, f.root.lines)
+	for _, s := range f.sections {
+		out = append(out, s.leading...)
+		out = append(out, s.header)
+		out = appendLines(out, s.lines)
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	text := strings.Join(out, f.eol)
+	if f.finalEOL {
+		text += f.eol
+	}
+	return []byte(text)
+}
+
+func appendLines(out []string, lines []*line) []string {
+	for _, l := range lines {
+		out = append(out, l.text())
+		out = append(out, l.sub...)
+	}
+	return out
+}
+
+// Sections returns every section in file order.
+func (f *File) Sections() []*Section {
+	return f.sections
+}
+
+// Section returns the first section with the given name, or nil.
+func (f *File) Section(name string) *Section {
+	name = normalizeName(name)
+	for _, s := range f.sections {
+		if s.name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// HasSection reports whether a section exists.
+func (f *File) HasSection(name string) bool {
+	return f.Section(name) != nil
+}
+
+// EnsureSection returns the named section, appending it to the end of the
+// file if it does not exist yet.
+func (f *File) EnsureSection(name string) *Section {
+	if s := f.Section(name); s != nil {
+		return s
+	}
+	name = normalizeName(name)
+
+	// Separate the new section from whatever precedes it by a blank line.
+	if prev := f.lastSection(); prev != nil {
+		if n := len(prev.lines); n == 0 || prev.lines[n-1].kind != lineBlank {
+			prev.lines = append(prev.lines, &line{kind: lineBlank})
+		}
+	}
+
+	s := &Section{name: name, header: "[" + name + "]"}
+	f.sections = append(f.sections, s)
+	f.finalEOL = true
+	return s
+}
+
+func (f *File) lastSection() *Section {
+	if len(f.sections) > 0 {
+		return f.sections[len(f.sections)-1]
+	}
+	if len(f.root.lines) > 0 {
+		return f.root
+	}
+	return nil
+}
+
+// DeleteSection removes every section with the given name, including the
+// comments directly above its header. It reports whether anything was removed.
+func (f *File) DeleteSection(name string) bool {
+	name = normalizeName(name)
+	kept := f.sections[:0]
+	removed, removedLast := false, false
+	for i, s := range f.sections {
+		if s.name == name {
+			removed = true
+			removedLast = i == len(f.sections)-1
+			continue
+		}
+		kept = append(kept, s)
+	}
+	f.sections = kept
+
+	// Removing the final section shouldn't leave a dangling blank line.
+	if last := f.lastSection(); removedLast && last != nil {
+		for n := len(last.lines); n > 0 && last.lines[n-1].kind == lineBlank; n-- {
+			last.lines = last.lines[:n-1]
+		}
+	}
+	return removed
+}
+
+// RenameSection changes a section header, keeping its contents in place.
+func (f *File) RenameSection(from, to string) bool {
+	s := f.Section(from)
+	if s == nil {
+		return false
+	}
+	s.name = normalizeName(to)
+	// Keep any comment after the old header.
+	s.header = "[" + s.name + "]" + s.header[strings.IndexByte(s.header, ']')+1:]
+	return true
+}
+
+// Name returns the normalized section name, e.g. "profile dev".
+func (s *Section) Name() string {
+	return s.name
+}
+
+// Keys returns the key names in file order.
+func (s *Section) Keys() []string {
+	var keys []string
+	seen := make(map[string]bool)
+	for _, l := range s.lines {
+		if l.kind == lineKey && !seen[l.key] {
+			seen[l.key] = true
+			keys = append(keys, l.key)
+		}
+	}
+	return keys
+}
+
+// find returns the last line defining key, which is the one that takes effect.
+func (s *Section) find(key string) *line {
+	for i := len(s.lines) - 1; i >= 0; i-- {
+		if l := s.lines[i]; l.kind == lineKey && l.key == key {
+			return l
+		}
+	}
+	return nil
+}
+
+// Has reports whether key is set.
+func (s *Section) Has(key string) bool {
+	return s != nil && s.find(key) != nil
+}
+
+// Value returns the value of key, or "" if unset. It is safe to call on a
+// nil Section.
+func (s *Section) Value(key string) string {
+	if s == nil {
+		return ""
+	}
+	if l := s.find(key); l != nil {
+		return l.value
+	}
+	return ""
+}
+
+// SubValues returns the nested settings under a key such as `s3 =`.
+func (s *Section) SubValues(key string) map[string]string {
+	if s == nil {
+		return nil
+	}
+	l := s.find(key)
+	if l == nil || len(l.sub) == 0 {
+		return nil
+	}
+	values := make(map[string]string)
+	for _, raw := range l.sub {
+		if k, v, ok := subKeyValue(raw); ok {
+			values[k] = v
+		}
+	}
+	return values
+}
+
+// subKeyValue parses a nested setting line, skipping indented comments.
+func subKeyValue(raw string) (key, value string, ok bool) {
+	trimmed := strings.TrimSpace(raw)
+	if trimmed == "" || trimmed[0] == '#' || trimmed[0] == ';' {
+		return "", "", false
+	}
+	return splitKeyValue(trimmed)
+}
+
+// SetSub assigns a nested setting such as `max_queue_size` under `s3 =`,
+// adding the parent key if needed. A new nested line copies the indentation
+// of its siblings.
+func (s *Section) SetSub(key, sub, value string) {
+	l := s.find(key)
+	if l == nil {
+		s.Set(key, "")
+		l = s.find(key)
+	} else if l.value != "" {
+		// A key holds either a value or nested settings, not both.
+		l.raw = strings.TrimRight(replaceValue(l.raw, ""), " \t")
+		l.value = ""
+	}
+
+	indent := "  "
+	for i, raw := range l.sub {
+		k, _, ok := subKeyValue(raw)
+		if !ok {
+			continue
+		}
+		indent = raw[:len(raw)-len(strings.TrimLeft(raw, " \t"))]
+		if k == sub {
+			l.sub[i] = replaceValue(raw, value)
+			return
+		}
+	}
+	l.sub = append(l.sub, indent+sub+" = "+value)
+}
+
+// DeleteSub removes a nested setting, and the parent key once it has no
+// nested settings left.
+func (s *Section) DeleteSub(key, sub string) bool {
+	l := s.find(key)
+	if l == nil {
+		return false
+	}
+	kept := l.sub[:0]
+	removed, remaining := false, 0
+	for _, raw := range l.sub {
+		if k, _, ok := subKeyValue(raw); ok {
+			if k == sub {
+				removed = true
+				continue
+			}
+			remaining++
+		}
+		kept = append(kept, raw)
+	}
+	l.sub = kept
+	if removed && remaining == 0 {
+		s.Delete(key)
+	}
+	return removed
+}
+
+// Map returns the section's keys and values. Nested settings are flattened
+// into their raw text so that any change to them is visible when comparing.
+func (s *Section) Map() map[string]string {
+	m := make(map[string]string)
+	for _, l := range s.lines {
+		if l.kind != lineKey {
+			continue
+		}
+		value := l.value
+		if len(l.sub) > 0 {
+			value += "\n" + strings.Join(l.sub, "\n")
+		}
+		m[l.key] = value
+	}
+	return m
+}
+
+// Set assigns key. An existing line keeps its original spacing around the
+// separator; a new key is added after the last key in the section.
+func (s *Section) Set(key, value string) {
+	if l := s.find(key); l != nil {
+		if l.value == value && len(l.sub) == 0 {
+			return
+		}
+		l.raw = replaceValue(l.raw, value)
+		l.value = value
+		l.sub = nil
+		return
+	}
+
+	insert := len(s.lines)
+	for insert > 0 && s.lines[insert-1].kind != lineKey {
+		insert--
+	}
+	if insert == 0 {
+		// No keys yet: go after any comments but before trailing blank lines.
+		insert = len(s.lines)
+		for insert > 0 && s.lines[insert-1].kind == lineBlank {
+			insert--
+		}
+	}
+	l := &line{kind: lineKey, key: key, value: value}
+	s.lines = append(s.lines[:insert], append([]*line{l}, s.lines[insert:]...)...)
+}
+
+// replaceValue swaps the value in a raw key line, keeping everything up to
+// and including the separator and the whitespace after it, and any trailing
+// comment.
+func replaceValue(raw, value string) string {
+	if raw == "" {
+		return ""
+	}
+	i := strings.IndexAny(raw, "=:")
+	if i < 0 {
+		return ""
+	}
+	end, comment := len(raw), ""
+	if c := inlineComment(raw[i+1:]); c >= 0 {
+		end = i + 1 + c
+		comment = raw[end:]
+		if value != "" && comment[0] == '#' {
+			comment = " " + comment
+		}
+	}
+	j := i + 1
+	for j < end && (raw[j] == ' ' || raw[j] == '\t') {
+		j++
+	}
+	prefix := raw[:j]
+	if j == i+1 && value != "" && strings.HasSuffix(raw[:i], " ") {
+		// `key =` with no value yet; keep the spacing symmetrical.
+		prefix += " "
+	}
+	return prefix + value + comment
+}
+
+// Delete removes key and any nested settings under it.
+func (s *Section) Delete(key string) bool {
+	kept := s.lines[:0]
+	removed := false
+	for _, l := range s.lines {
+		if l.kind == lineKey && l.key == key {
+			removed = true
+			continue
+		}
+		kept = append(kept, l)
+	}
+	s.lines = kept
+	return removed
+}
+
+// ReplaceKeys makes s hold exactly the keys of src, copying their lines
+// verbatim. Comments and blank lines in s are left alone.
+func (s *Section) ReplaceKeys(src *Section) {
+	for _, key := range s.Keys() {
+		s.Delete(key)
+	}
+	if src == nil {
+		return
+	}
+	for _, l := range src.lines {
+		if l.kind != lineKey {
+			continue
+		}
+		s.Set(l.key, l.value)
+		dst := s.find(l.key)
+		dst.raw = l.raw
+		dst.sub = append([]string(nil), l.sub...)
+	}
+}
@@ -0,0 +1,176 @@
+package inifile
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files")
+
+func load(t *testing.T, name string) *File {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := Parse(data)
+	if err != nil {
+		t.Fatalf("parse %s: %v", name, err)
+	}
+	return f
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, name := range []string{"config.ini", "credentials.ini", "comments.ini", "nested.ini"} {
+		t.Run(name, func(t *testing.T) {
+			want, _ := os.ReadFile(filepath.Join("testdata", name))
+			if got := load(t, name).Bytes(); !bytes.Equal(got, want) {
+				t.Errorf("round trip changed the file:\n%s", got)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	f := load(t, "config.ini")
+
+	if got := f.Section("profile legacy").Value("region"); got != "eu-west-1" {
+		t.Errorf("legacy region = %q", got)
+	}
+	if got := f.Section("sso-session corp").Value("sso_region"); got != "us-east-1" {
+		t.Errorf("sso_region = %q", got)
+	}
+	if got := f.Section("profile dev").SubValues("s3")["max_queue_size"]; got != "10000" {
+		t.Errorf("s3.max_queue_size = %q", got)
+	}
+	if got := f.Section("services local-dynamo").SubValues("dynamodb")["endpoint_url"]; got != "http://localhost:8000" {
+		t.Errorf("dynamodb.endpoint_url = %q", got)
+	}
+	if got := f.Section("profile prod").Value("role_arn"); got != "arn:aws:iam::222222222222:role/Admin" {
+		t.Errorf("role_arn = %q", got)
+	}
+}
+
+func TestParseComments(t *testing.T) {
+	f := load(t, "comments.ini")
+
+	cases := []struct{ section, key, want string }{
+		{"default", "region", "us-east-1"},
+		{"profile dev", "sso_start_url", "https://corp.awsapps.com/start/#/"},
+		{"profile dev", "credential_process", `/bin/sh -c "fetch ; decode"`},
+		{"profile dev", "cli_pager", ""},
+	}
+	for _, tc := range cases {
+		if got := f.Section(tc.section).Value(tc.key); got != tc.want {
+			t.Errorf("[%s] %s = %q, want %q", tc.section, tc.key, got, tc.want)
+		}
+	}
+}
+
+func TestParseNestedComments(t *testing.T) {
+	s3 := load(t, "nested.ini").Section("profile dev").SubValues("s3")
+	if len(s3) != 2 || s3["max_concurrent_requests"] != "20" || s3["max_queue_size"] != "10000" {
+		t.Errorf("s3 = %v", s3)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, input := range []string{"[profile dev\nregion = x\n", "[default]\nnot a setting\n", "[default] region = x\n"} {
+		if _, err := Parse([]byte(input)); err == nil {
+			t.Errorf("expected an error parsing %q", input)
+		}
+	}
+}
+
+func TestGolden(t *testing.T) {
+	cases := []struct {
+		name  string
+		input string
+		edit  func(f *File)
+	}{
+		{"set-existing", "config.ini", func(f *File) {
+			f.Section("default").Set("region", "eu-central-1")
+			f.Section("sso-session corp").Set("sso_region", "eu-west-1")
+		}},
+		{"add-key", "config.ini", func(f *File) {
+			f.Section("profile dev").Set("output", "text")
+			f.Section("profile prod").Set("region", "us-east-2")
+		}},
+		{"add-section", "config.ini", func(f *File) {
+			f.EnsureSection("profile staging").Set("region", "ca-central-1")
+		}},
+		{"delete-section", "config.ini", func(f *File) {
+			f.DeleteSection("sso-session corp")
+			f.DeleteSection("profile prod")
+		}},
+		{"delete-nested-key", "config.ini", func(f *File) {
+			f.Section("profile dev").Delete("s3")
+			f.Section("services local-dynamo").Set("dynamodb", "")
+		}},
+		{"set-nested", "config.ini", func(f *File) {
+			f.Section("profile dev").SetSub("s3", "max_queue_size", "500")
+			f.Section("profile dev").SetSub("s3", "multipart_threshold", "64MB")
+			f.Section("services local-dynamo").SetSub("sqs", "endpoint_url", "http://localhost:9324")
+			f.Section("default").SetSub("output", "color", "off")
+		}},
+		{"delete-nested-setting", "config.ini", func(f *File) {
+			f.Section("profile dev").DeleteSub("s3", "max_queue_size")
+			f.Section("services local-dynamo").DeleteSub("dynamodb", "endpoint_url")
+		}},
+		{"replace-keys", "config.ini", func(f *File) {
+			f.Section("default").ReplaceKeys(f.Section("profile dev"))
+		}},
+		{"rename-section", "config.ini", func(f *File) {
+			f.RenameSection("profile legacy", "profile old")
+		}},
+		{"set-commented", "comments.ini", func(f *File) {
+			f.Section("default").Set("region", "eu-central-1")
+			f.Section("profile dev").Set("cli_pager", "less")
+			f.Section("profile dev").Set("region", "us-east-2")
+			f.RenameSection("profile dev", "profile sandbox")
+		}},
+		{"set-nested-commented", "nested.ini", func(f *File) {
+			f.Section("profile dev").SetSub("s3", "max_queue_size", "500")
+			f.Section("profile dev").SetSub("s3", "multipart_threshold", "64MB")
+			f.Section("profile dev").DeleteSub("s3", "max_concurrent_requests")
+		}},
+		{"credentials-crlf", "credentials.ini", func(f *File) {
+			f.Section("ci").Set("aws_secret_access_key", "rotated")
+			f.EnsureSection("deploy").Set("aws_access_key_id", "AKIA3")
+		}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := load(t, tc.input)
+			tc.edit(f)
+			got := f.Bytes()
+
+			golden := filepath.Join("testdata", tc.name+".golden")
+			if *update {
+				if err := os.WriteFile(golden, got, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("output differs from %s:\n%s", golden, got)
+			}
+
+			// The edited file must parse back to the same thing.
+			again, err := Parse(got)
+			if err != nil {
+				t.Fatalf("re-parse: %v", err)
+			}
+			if !bytes.Equal(again.Bytes(), got) {
+				t.Error("edited output does not round-trip")
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+// Package manifest reads the declarative team manifest used by
+// `gsd config sync`: SSO sessions, accounts, the roles to create profiles
+// for, and the rule that names those profiles.
+package manifest
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/aphexlog/gsd/internal/profiles"
+	"gopkg.in/yaml.v3"
+)
+
+// Version is the manifest format this package understands.
+const Version = 1
+
+// DefaultTemplate names profiles when the manifest has no naming rule.
+const DefaultTemplate = "{account}-{role}"
+
+// Manifest is the canonical list of accounts and roles a team maintains.
+type Manifest struct {
+	Version int `yaml:"version"`
+
+	// Name tags the profiles this manifest manages, so several manifests
+	// and hand-written profiles can live in one config file.
+	Name string `yaml:"name"`
+
+	SSOSessions []SSOSession      `yaml:"sso_sessions"`
+	Accounts    []Account         `yaml:"accounts"`
+	Roles       []Role            `yaml:"roles"`
+	Naming      Naming            `yaml:"naming"`
+	Defaults    map[string]string `yaml:"defaults"`
+}
+
+// SSOSession describes an sso-session block.
+type SSOSession struct {
+	Name               string `yaml:"name"`
+	StartURL           string `yaml:"start_url"`
+	Region             string `yaml:"region"`
+	RegistrationScopes string `yaml:"registration_scopes"`
+}
+
+// Account is an AWS account reachable through an SSO session.
+type Account struct {
+	Name       string `yaml:"name"`
+	ID         string `yaml:"id"`
+	Region     string `yaml:"region"`
+	SSOSession string `yaml:"sso_session"`
+}
+
+// Role is a permission set. Profiles are created for it in every listed
+// account, or in all accounts when none are listed.
+type Role struct {
+	Name     string   `yaml:"name"`
+	Alias    string   `yaml:"alias"`
+	Accounts []string `yaml:"accounts"`
+	Region   string   `yaml:"region"`
+}
+
+// Naming is the rule that turns an account and role into a profile name.
+// The template may use {account}, {account_id}, {role} and {session}.
+type Naming struct {
+	Template  string `yaml:"template"`
+	Lowercase bool   `yaml:"lowercase"`
+}
+
+var accountIDPattern = regexp.MustCompile(`^\d{12}$`)
+
+// Load reads and validates a manifest file.
+func Load(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if err := m.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &m, nil
+}
+
+// Validate checks the manifest for missing fields and dangling references.
+func (m *Manifest) Validate() error {
+	if m.Version != Version {
+		return fmt.Errorf("unsupported manifest version %d (expected %d)", m.Version, Version)
+	}
+	if m.Name == "" {
+		return fmt.Errorf("manifest needs a name to tag the profiles it manages")
+	}
+	if len(m.SSOSessions) == 0 {
+		return fmt.Errorf("manifest has no sso_sessions")
+	}
+
+	sessions := make(map[string]bool)
+	for _, sess := range m.SSOSessions {
+		if sess.Name == "" || sess.StartURL == "" || sess.Region == "" {
+			return fmt.Errorf("sso_session '%s' needs a name, start_url and region", sess.Name)
+		}
+		if sessions[sess.Name] {
+			return fmt.Errorf("sso_session '%s' is listed twice", sess.Name)
+		}
+		sessions[sess.Name] = true
+	}
+
+	accounts := make(map[string]bool)
+	for _, acct := range m.Accounts {
+		if acct.Name == "" {
+			return fmt.Errorf("account %s needs a name", acct.ID)
+		}
+		if !accountIDPattern.MatchString(acct.ID) {
+			return fmt.Errorf("account '%s' needs a 12-digit id", acct.Name)
+		}
+		if accounts[acct.Name] {
+			return fmt.Errorf("account '%s' is listed twice", acct.Name)
+		}
+		accounts[acct.Name] = true
+		if acct.SSOSession == "" && len(m.SSOSessions) > 1 {
+			return fmt.Errorf("account '%s' must name its sso_session", acct.Name)
+		}
+		if acct.SSOSession != "" && !sessions[acct.SSOSession] {
+			return fmt.Errorf("account '%s' uses unknown sso_session '%s'", acct.Name, acct.SSOSession)
+		}
+	}
+
+	for _, role := range m.Roles {
+		if role.Name == "" {
+			return fmt.Errorf("every role needs a name")
+		}
+		for _, acct := range role.Accounts {
+			if !accounts[acct] {
+				return fmt.Errorf("role '%s' lists unknown account '%s'", role.Name, acct)
+			}
+		}
+	}
+
+	seen := make(map[string]string)
+	for _, p := range m.Profiles() {
+		if p.Name == "" || strings.ContainsAny(p.Name, "[] \t") {
+			return fmt.Errorf("naming template gives the invalid profile name '%s'", p.Name)
+		}
+		who := p.Settings["sso_account_id"] + "/" + p.Settings["sso_role_name"]
+		if prev, ok := seen[p.Name]; ok {
+			return fmt.Errorf("naming template gives '%s' to both %s and %s", p.Name, prev, who)
+		}
+		seen[p.Name] = who
+	}
+	return nil
+}
+
+// SessionSections returns the sso-session blocks the manifest describes,
+// tagged as managed by it.
+func (m *Manifest) SessionSections() []profiles.BundleSection {
+	var sections []profiles.BundleSection
+	for _, sess := range m.SSOSessions {
+		scopes := sess.RegistrationScopes
+		if scopes == "" {
+			scopes = profiles.DefaultRegistrationScopes
+		}
+		sections = append(sections, profiles.BundleSection{
+			Name: sess.Name,
+			Settings: map[string]string{
+				"sso_start_url":           sess.StartURL,
+				"sso_region":              sess.Region,
+				"sso_registration_scopes": scopes,
+				profiles.ManagedByKey:     m.Name,
+			},
+		})
+	}
+	return sections
+}
+
+// Profiles expands every role in every account it applies to into the
+// profile the manifest wants, tagged as managed by it.
+func (m *Manifest) Profiles() []profiles.BundleSection {
+	var sections []profiles.BundleSection
+	for _, acct := range m.Accounts {
+		session := acct.SSOSession
+		if session == "" && len(m.SSOSessions) == 1 {
+			session = m.SSOSessions[0].Name
+		}
+
+		for _, role := range m.Roles {
+			if !role.appliesTo(acct.Name) {
+				continue
+			}
+
+			settings := make(map[string]string)
+			for key, value := range m.Defaults {
+				settings[key] = value
+			}
+			if acct.Region != "" {
+				settings["region"] = acct.Region
+			}
+			if role.Region != "" {
+				settings["region"] = role.Region
+			}
+			settings["sso_session"] = session
+			settings["sso_account_id"] = acct.ID
+			settings["sso_role_name"] = role.Name
+			settings[profiles.ManagedByKey] = m.Name
+
+			sections = append(sections, profiles.BundleSection{
+				Name:     m.profileName(acct, role, session),
+				Settings: settings,
+			})
+		}
+	}
+	return sections
+}
+
+func (r Role) appliesTo(account string) bool {
+	if len(r.Accounts) == 0 {
+		return true
+	}
+	for _, name := range r.Accounts {
+		if name == account {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Manifest) profileName(acct Account, role Role, session string) string {
+	template := m.Naming.Template
+	if template == "" {
+		template = DefaultTemplate
+	}
+	roleName := role.Alias
+	if roleName == "" {
+		roleName = role.Name
+	}
+	name := strings.NewReplacer(
+		"{account}", acct.Name,
+		"{account_id}", acct.ID,
+		"{role}", roleName,
+		"{session}", session,
+	).Replace(template)
+	if m.Naming.Lowercase {
+		name = strings.ToLower(name)
+	}
+	return name
+}
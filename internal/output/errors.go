@@ -0,0 +1,43 @@
+package output
+
+import "fmt"
+
+// Error codes reported in structured error output.
+const (
+	CodeError           = "error"
+	CodeInvalidArgument = "invalid_argument"
+	CodeNotFound        = "not_found"
+	CodeConflict        = "conflict"
+	CodeCancelled       = "cancelled"
+	CodeAWS             = "aws_error"
+	CodeUnauthenticated = "unauthenticated"
+	CodeNoTerminal      = "no_terminal"
+)
+
+// Error is a failure with a stable code that scripts can match on.
+type Error struct {
+	Code    string `json:"code" yaml:"code"`
+	Message string `json:"message" yaml:"message"`
+	Cause   error  `json:"-" yaml:"-"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
+// Errorf creates an Error with the given code.
+func Errorf(code, format string, args ...any) *Error {
+	err := fmt.Errorf(format, args...)
+	return &Error{Code: code, Message: err.Error(), Cause: unwrap(err)}
+}
+
+func unwrap(err error) error {
+	if u, ok := err.(interface{ Unwrap() error }); ok {
+		return u.Unwrap()
+	}
+	return nil
+}
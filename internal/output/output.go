@@ -0,0 +1,91 @@
+// Package output renders command results and errors as text, JSON or YAML
+// so that gsd is usable both interactively and from scripts.
+package output
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Format is an output format selected with --output.
+type Format string
+
+const (
+	Text Format = "text"
+	JSON Format = "json"
+	YAML Format = "yaml"
+)
+
+// ParseFormat validates a --output value.
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(s); f {
+	case Text, JSON, YAML:
+		return f, nil
+	case "":
+		return Text, nil
+	default:
+		return "", Errorf(CodeInvalidArgument, "unknown output format '%s' (use text, json or yaml)", s)
+	}
+}
+
+// Texter is implemented by results that have a human readable form. Results
+// without one are printed as YAML in text mode.
+type Texter interface {
+	Text(w io.Writer)
+}
+
+// Renderer prints results and errors in the selected format.
+type Renderer struct {
+	Format Format
+	Out    io.Writer
+	Err    io.Writer
+}
+
+// Render prints a command result.
+func (r *Renderer) Render(result any) error {
+	switch r.Format {
+	case JSON:
+		enc := json.NewEncoder(r.Out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(result)
+	case YAML:
+		return encodeYAML(r.Out, result)
+	default:
+		if t, ok := result.(Texter); ok {
+			t.Text(r.Out)
+			return nil
+		}
+		return encodeYAML(r.Out, result)
+	}
+}
+
+// RenderError prints an error. Structured formats write an
+// {"error": {...}} document to Out so scripts only need to parse one stream.
+func (r *Renderer) RenderError(err error) {
+	// Keep the code of the underlying Error but the full wrapped message.
+	e := &Error{Code: CodeError, Message: err.Error()}
+	var coded *Error
+	if errors.As(err, &coded) {
+		e.Code = coded.Code
+	}
+
+	switch r.Format {
+	case JSON, YAML:
+		r.Render(map[string]*Error{"error": e})
+	default:
+		fmt.Fprintf(r.Err, "❌ %s\n", e.Message)
+	}
+}
+
+func encodeYAML(w io.Writer, v any) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	return enc.Close()
+}
@@ -0,0 +1,104 @@
+// Package pin reads and writes the per-directory files that name the AWS
+// profile to use inside a project: .gsd.yaml, or the one-line .gsd-profile.
+package pin
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aphexlog/gsd/internal/fsutil"
+	"gopkg.in/yaml.v3"
+)
+
+// File names, in order of precedence within one directory.
+const (
+	YAMLFile    = ".gsd.yaml"
+	ProfileFile = ".gsd-profile"
+)
+
+// File is a pin file found on disk.
+type File struct {
+	Path    string `yaml:"-"`
+	Profile string `yaml:"profile"`
+	Region  string `yaml:"region,omitempty"`
+
+	// Data is the raw content, which is what gets trusted.
+	Data []byte `yaml:"-"`
+}
+
+// Find returns the pin file closest to dir, looking in dir and then each
+// parent directory. It returns nil if there is none.
+func Find(dir string) (*File, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		for _, name := range []string{YAMLFile, ProfileFile} {
+			path := filepath.Join(dir, name)
+			data, err := os.ReadFile(path)
+			if errors.Is(err, fs.ErrNotExist) || isDir(err, path) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			return Parse(path, data)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+func isDir(err error, path string) bool {
+	if err == nil {
+		return false
+	}
+	info, statErr := os.Stat(path)
+	return statErr == nil && info.IsDir()
+}
+
+// Parse reads a pin file's content. .gsd-profile holds just the profile
+// name; blank lines and # comments are ignored.
+func Parse(path string, data []byte) (*File, error) {
+	f := &File{Path: path, Data: data}
+	if filepath.Base(path) == ProfileFile {
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line != "" && !strings.HasPrefix(line, "#") {
+				f.Profile = line
+				break
+			}
+		}
+	} else if err := yaml.Unmarshal(data, f); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if f.Profile == "" {
+		return nil, fmt.Errorf("%s does not name a profile", path)
+	}
+	return f, nil
+}
+
+// Write creates or replaces the .gsd.yaml in dir.
+func Write(dir, profile, region string) (*File, error) {
+	f := &File{Path: filepath.Join(dir, YAMLFile), Profile: profile, Region: region}
+	data, err := yaml.Marshal(f)
+	if err != nil {
+		return nil, err
+	}
+	f.Data = append([]byte("# Written by 'gsd pin'. Commit it so everyone uses the same AWS profile here.\n"), data...)
+	if err := fsutil.WriteFileAtomic(f.Path, f.Data, 0644); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
@@ -0,0 +1,87 @@
+package pin
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFind(t *testing.T) {
+	cases := []struct {
+		name  string
+		files map[string]string // relative path -> content
+		dir   string
+		want  string // relative path of the file found, "" for none
+		err   bool
+	}{
+		{"none", nil, "a/b", "", false},
+		{"same directory", map[string]string{"a/b/.gsd-profile": "dev\n"}, "a/b", "a/b/.gsd-profile", false},
+		{"parent directory", map[string]string{"a/.gsd.yaml": "profile: dev\n"}, "a/b", "a/.gsd.yaml", false},
+		{"nearest wins", map[string]string{".gsd.yaml": "profile: top\n", "a/.gsd-profile": "dev\n"}, "a/b", "a/.gsd-profile", false},
+		{"yaml before profile file", map[string]string{"a/.gsd.yaml": "profile: yaml\n", "a/.gsd-profile": "plain\n"}, "a", "a/.gsd.yaml", false},
+		{"directory named like a pin", map[string]string{"a/.gsd.yaml/x": "", "a/.gsd-profile": "dev\n"}, "a", "a/.gsd-profile", false},
+		{"broken file stops the search", map[string]string{"a/.gsd.yaml": "region: x\n", ".gsd.yaml": "profile: top\n"}, "a", "", true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			root := t.TempDir()
+			for name, content := range tc.files {
+				path := filepath.Join(root, name)
+				if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			dir := filepath.Join(root, tc.dir)
+			if err := os.MkdirAll(dir, 0700); err != nil {
+				t.Fatal(err)
+			}
+
+			f, err := Find(dir)
+			if (err != nil) != tc.err {
+				t.Fatalf("Find() error = %v, want error %v", err, tc.err)
+			}
+			got := ""
+			if f != nil {
+				got, _ = filepath.Rel(root, f.Path)
+			}
+			if got != filepath.FromSlash(tc.want) {
+				t.Errorf("Find() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		name, file, data string
+		profile, region  string
+		err              bool
+	}{
+		{"profile file", ProfileFile, "dev\n", "dev", "", false},
+		{"profile file with comments", ProfileFile, "# team default\n\n  dev  \nprod\n", "dev", "", false},
+		{"empty profile file", ProfileFile, "# nothing\n", "", "", true},
+		{"yaml", YAMLFile, "profile: dev\nregion: eu-west-1\n", "dev", "eu-west-1", false},
+		{"yaml without profile", YAMLFile, "region: eu-west-1\n", "", "", true},
+		{"bad yaml", YAMLFile, "profile: [dev\n", "", "", true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := Parse(filepath.Join("/p", tc.file), []byte(tc.data))
+			if (err != nil) != tc.err {
+				t.Fatalf("Parse() error = %v, want error %v", err, tc.err)
+			}
+			if err != nil {
+				return
+			}
+			if f.Profile != tc.profile || f.Region != tc.region {
+				t.Errorf("Parse() = %q/%q, want %q/%q", f.Profile, f.Region, tc.profile, tc.region)
+			}
+			if string(f.Data) != tc.data {
+				t.Error("Parse() did not keep the raw content")
+			}
+		})
+	}
+}
@@ -0,0 +1,265 @@
+package profiles
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// BundleVersion is the bundle format written by Export.
+const BundleVersion = 1
+
+// Bundle is a portable set of profiles with the SSO sessions and services
+// sections they use, as written by `gsd config export`.
+type Bundle struct {
+	Version     int             `json:"version" yaml:"version"`
+	SSOSessions []BundleSection `json:"sso_sessions,omitempty" yaml:"sso_sessions,omitempty"`
+	Services    []BundleSection `json:"services,omitempty" yaml:"services,omitempty"`
+	Profiles    []BundleSection `json:"profiles,omitempty" yaml:"profiles,omitempty"`
+}
+
+// BundleSection is one section of a bundle. Nested settings are flattened
+// to parent.key. Credentials are only present in bundles exported with
+// secrets.
+type BundleSection struct {
+	Name        string            `json:"name" yaml:"name"`
+	Settings    map[string]string `json:"settings,omitempty" yaml:"settings,omitempty"`
+	Credentials map[string]string `json:"credentials,omitempty" yaml:"credentials,omitempty"`
+}
+
+// referenceKeys are the profile settings that refer to a section by name.
+var referenceKeys = map[SectionKind]string{
+	ProfileSection:    "source_profile",
+	SSOSessionSection: "sso_session",
+	ServicesSection:   "services",
+}
+
+// Export collects the profiles accepted by match together with the SSO
+// sessions and services sections they refer to. Credentials are included
+// only when includeSecrets is set.
+func (s *Store) Export(match func(name string) bool, includeSecrets bool) *Bundle {
+	b := &Bundle{Version: BundleVersion}
+	sessions := make(map[string]bool)
+	services := make(map[string]bool)
+
+	for _, name := range s.Names() {
+		if !match(name) {
+			continue
+		}
+		section := BundleSection{
+			Name:     name,
+			Settings: s.Settings(Target{ProfileSection, name}),
+		}
+		if includeSecrets {
+			section.Credentials = s.Credentials(name)
+		} else {
+			// Static keys are sometimes kept in the config file too.
+			for key := range section.Settings {
+				if IsCredentialKey(key) {
+					delete(section.Settings, key)
+				}
+			}
+		}
+		if len(section.Settings) == 0 && len(section.Credentials) == 0 {
+			// Nothing left of a credentials-only profile without its secrets.
+			continue
+		}
+		if v := section.Settings[referenceKeys[SSOSessionSection]]; v != "" {
+			sessions[v] = true
+		}
+		if v := section.Settings[referenceKeys[ServicesSection]]; v != "" {
+			services[v] = true
+		}
+		b.Profiles = append(b.Profiles, section)
+	}
+
+	for _, name := range s.SSOSessionNames() {
+		if sessions[name] {
+			b.SSOSessions = append(b.SSOSessions, BundleSection{Name: name, Settings: s.Settings(Target{SSOSessionSection, name})})
+		}
+	}
+	for _, name := range s.ServicesNames() {
+		if services[name] {
+			b.Services = append(b.Services, BundleSection{Name: name, Settings: s.Settings(Target{ServicesSection, name})})
+		}
+	}
+	return b
+}
+
+// Validate checks a bundle read from a file.
+func (b *Bundle) Validate() error {
+	if b.Version != BundleVersion {
+		return fmt.Errorf("unsupported bundle version %d (expected %d)", b.Version, BundleVersion)
+	}
+	for _, kind := range []SectionKind{SSOSessionSection, ServicesSection, ProfileSection} {
+		seen := make(map[string]bool)
+		for _, section := range *b.Sections(kind) {
+			if section.Name == "" {
+				return fmt.Errorf("bundle has a %s without a name", kind)
+			}
+			if err := ValidateName(section.Name); err != nil {
+				return fmt.Errorf("bundle has an invalid %s name '%s'", kind, section.Name)
+			}
+			for _, settings := range []map[string]string{section.Settings, section.Credentials} {
+				if err := validateSettings(settings); err != nil {
+					return fmt.Errorf("bundle %s '%s': %w", kind, section.Name, err)
+				}
+			}
+			if seen[section.Name] {
+				return fmt.Errorf("bundle has %s '%s' twice", kind, section.Name)
+			}
+			seen[section.Name] = true
+		}
+	}
+	return nil
+}
+
+// validateSettings rejects keys and values that would break out of their
+// line in the INI file.
+func validateSettings(settings map[string]string) error {
+	for key, value := range settings {
+		if key == "" || strings.ContainsAny(key, "=[]#; \t\r\n") {
+			return fmt.Errorf("invalid setting name '%s'", key)
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("setting %s has a value spanning several lines", key)
+		}
+	}
+	return nil
+}
+
+// Sections returns the bundle's list of sections of the given kind.
+func (b *Bundle) Sections(kind SectionKind) *[]BundleSection {
+	switch kind {
+	case SSOSessionSection:
+		return &b.SSOSessions
+	case ServicesSection:
+		return &b.Services
+	default:
+		return &b.Profiles
+	}
+}
+
+// Copy returns a deep copy of the bundle, so one can be renamed in without
+// touching the other.
+func (b *Bundle) Copy() *Bundle {
+	c := &Bundle{Version: b.Version}
+	for _, kind := range []SectionKind{SSOSessionSection, ServicesSection, ProfileSection} {
+		for _, section := range *b.Sections(kind) {
+			*c.Sections(kind) = append(*c.Sections(kind), BundleSection{
+				Name:        section.Name,
+				Settings:    copyMap(section.Settings),
+				Credentials: copyMap(section.Credentials),
+			})
+		}
+	}
+	return c
+}
+
+func copyMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	c := make(map[string]string, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+// Rename gives a section of the bundle a new name and points the profiles in
+// the bundle that refer to it at the new name.
+func (b *Bundle) Rename(kind SectionKind, from, to string) {
+	sections := *b.Sections(kind)
+	for i := range sections {
+		if sections[i].Name == from {
+			sections[i].Name = to
+		}
+	}
+	key := referenceKeys[kind]
+	for _, p := range b.Profiles {
+		if p.Settings[key] == from {
+			p.Settings[key] = to
+		}
+	}
+}
+
+// ImportStatus says how a bundle section relates to what is already there.
+type ImportStatus string
+
+const (
+	ImportNew       ImportStatus = "new"
+	ImportUnchanged ImportStatus = "unchanged"
+	ImportConflict  ImportStatus = "conflict"
+)
+
+// CompareImport compares a bundle section with the existing section of the
+// same name and returns the differences as +, - and ~ lines. Credential
+// values are masked.
+func (s *Store) CompareImport(kind SectionKind, section BundleSection) (ImportStatus, []string) {
+	target := Target{kind, section.Name}
+	if !s.Exists(target) {
+		diff := diffSettings(nil, section.Settings, false)
+		return ImportNew, append(diff, diffSettings(nil, section.Credentials, true)...)
+	}
+
+	diff := diffSettings(s.Settings(target), section.Settings, false)
+	if section.Credentials != nil {
+		diff = append(diff, diffSettings(s.Credentials(section.Name), section.Credentials, true)...)
+	}
+	if len(diff) == 0 {
+		return ImportUnchanged, nil
+	}
+	return ImportConflict, diff
+}
+
+// Import writes a bundle section, replacing the settings of an existing
+// section of the same name. Existing credentials are kept unless the bundle
+// carries its own.
+func (s *Store) Import(kind SectionKind, section BundleSection) {
+	target := Target{kind, section.Name}
+	// A profile with only credentials gets no empty config section.
+	if len(section.Settings) > 0 || s.config.HasSection(target.configSection()) {
+		s.ReplaceSettings(target, section.Settings)
+	}
+	if kind == ProfileSection && section.Credentials != nil {
+		s.ReplaceCredentials(section.Name, section.Credentials)
+	}
+}
+
+func diffSettings(before, after map[string]string, secret bool) []string {
+	show := func(v string) string {
+		if secret {
+			return "****"
+		}
+		return v
+	}
+
+	keys := make(map[string]bool)
+	for key := range before {
+		keys[key] = true
+	}
+	for key := range after {
+		keys[key] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	var diff []string
+	for _, key := range sorted {
+		old, hadOld := before[key]
+		cur, hasNew := after[key]
+		switch {
+		case !hadOld:
+			diff = append(diff, fmt.Sprintf("+ %s = %s", key, show(cur)))
+		case !hasNew:
+			diff = append(diff, fmt.Sprintf("- %s = %s", key, show(old)))
+		case old != cur:
+			diff = append(diff, fmt.Sprintf("~ %s = %s → %s", key, show(old), show(cur)))
+		}
+	}
+	return diff
+}
@@ -0,0 +1,28 @@
+package profiles
+
+import "testing"
+
+func TestBundleValidate(t *testing.T) {
+	cases := []struct {
+		name    string
+		section BundleSection
+		ok      bool
+	}{
+		{"plain", BundleSection{Name: "dev", Settings: map[string]string{"region": "us-east-1", "s3.max_queue_size": "10"}}, true},
+		{"no name", BundleSection{Settings: map[string]string{"region": "us-east-1"}}, false},
+		{"bracket in name", BundleSection{Name: "dev]"}, false},
+		{"newline in name", BundleSection{Name: "dev\n[default"}, false},
+		{"padded name", BundleSection{Name: " dev"}, false},
+		{"newline in value", BundleSection{Name: "dev", Settings: map[string]string{"region": "us-east-1\n[profile x]"}}, false},
+		{"newline in credential", BundleSection{Name: "dev", Credentials: map[string]string{"aws_secret_access_key": "a\rb"}}, false},
+		{"equals in key", BundleSection{Name: "dev", Settings: map[string]string{"a=b": "c"}}, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			b := &Bundle{Version: BundleVersion, Profiles: []BundleSection{tc.section}}
+			if err := b.Validate(); (err == nil) != tc.ok {
+				t.Errorf("Validate() = %v, want ok %v", err, tc.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package profiles
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MissingSourceError reports a source_profile that names no profile.
+type MissingSourceError struct {
+	Profile string
+	Source  string
+}
+
+func (e *MissingSourceError) Error() string {
+	return fmt.Sprintf("source_profile '%s' of profile '%s' does not exist", e.Source, e.Profile)
+}
+
+// CycleError reports source_profile links that loop back on themselves.
+type CycleError struct {
+	Chain []string
+}
+
+func (e *CycleError) Error() string {
+	return "source_profile cycle: " + strings.Join(e.Chain, " → ")
+}
+
+// RoleChain follows source_profile links from name and returns every profile
+// visited, starting with name itself. It fails if a link points at a missing
+// profile or the chain loops. A profile naming itself as source_profile is
+// allowed when it also holds static keys, as the AWS SDKs permit.
+func (s *Store) RoleChain(name string) ([]string, error) {
+	chain := []string{name}
+	seen := map[string]bool{name: true}
+
+	current := name
+	for {
+		p, ok := s.Get(current)
+		if !ok {
+			return chain, fmt.Errorf("profile '%s' not found", current)
+		}
+		if p.RoleARN == "" || p.SourceProfile == "" {
+			return chain, nil
+		}
+
+		next := p.SourceProfile
+		if next == current && p.AccessKeyID != "" {
+			return chain, nil
+		}
+		if !s.Has(next) {
+			return chain, &MissingSourceError{Profile: current, Source: next}
+		}
+		if seen[next] {
+			return chain, &CycleError{Chain: append(chain, next)}
+		}
+		seen[next] = true
+		chain = append(chain, next)
+		current = next
+	}
+}
@@ -0,0 +1,97 @@
+package profiles
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+const chainConfig = `[profile admin]
+role_arn = arn:aws:iam::111111111111:role/Admin
+source_profile = base
+
+[profile base]
+region = us-east-1
+
+[profile orphan]
+role_arn = arn:aws:iam::111111111111:role/Admin
+source_profile = gone
+
+[profile self]
+role_arn = arn:aws:iam::111111111111:role/Admin
+source_profile = self
+
+[profile self-keys]
+role_arn = arn:aws:iam::111111111111:role/Admin
+source_profile = self-keys
+
+[profile a]
+role_arn = arn:aws:iam::111111111111:role/A
+source_profile = b
+
+[profile b]
+role_arn = arn:aws:iam::111111111111:role/B
+source_profile = c
+
+[profile c]
+role_arn = arn:aws:iam::111111111111:role/C
+source_profile = a
+
+[profile ec2]
+role_arn = arn:aws:iam::111111111111:role/Admin
+credential_source = Ec2InstanceMetadata
+`
+
+const chainCredentials = `[base]
+aws_access_key_id = AKIA1
+aws_secret_access_key = secret
+
+[self-keys]
+aws_access_key_id = AKIA2
+aws_secret_access_key = secret
+`
+
+func TestRoleChain(t *testing.T) {
+	s := testStore(t, chainConfig, chainCredentials)
+	cases := []struct {
+		name    string
+		want    []string
+		missing *MissingSourceError
+		cycle   []string
+	}{
+		{name: "admin", want: []string{"admin", "base"}},
+		{name: "base", want: []string{"base"}},
+		{name: "orphan", want: []string{"orphan"}, missing: &MissingSourceError{Profile: "orphan", Source: "gone"}},
+		{name: "self", want: []string{"self"}, cycle: []string{"self", "self"}},
+		{name: "self-keys", want: []string{"self-keys"}},
+		{name: "a", want: []string{"a", "b", "c"}, cycle: []string{"a", "b", "c", "a"}},
+		{name: "ec2", want: []string{"ec2"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			chain, err := s.RoleChain(tc.name)
+			if !slices.Equal(chain, tc.want) {
+				t.Errorf("chain = %v, want %v", chain, tc.want)
+			}
+
+			var missing *MissingSourceError
+			var cycle *CycleError
+			switch {
+			case tc.missing != nil:
+				if !errors.As(err, &missing) || *missing != *tc.missing {
+					t.Errorf("err = %v, want %v", err, tc.missing)
+				}
+			case tc.cycle != nil:
+				if !errors.As(err, &cycle) || !slices.Equal(cycle.Chain, tc.cycle) {
+					t.Errorf("err = %v, want a cycle through %v", err, tc.cycle)
+				}
+			case err != nil:
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+
+	if _, err := s.RoleChain("nope"); err == nil {
+		t.Error("expected an error for a profile that doesn't exist")
+	}
+}
@@ -0,0 +1,106 @@
+package profiles
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/aphexlog/gsd/internal/inifile"
+)
+
+// Change describes one section that differs between the loaded files and the
+// pending in-memory state.
+type Change struct {
+	File    string // "config" or "credentials"
+	Section string
+	Action  string // "added", "removed" or "modified"
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s [%s] in %s", c.Action, c.Section, c.File)
+}
+
+// Changes lists the sections that Save would add, remove or modify.
+func (s *Store) Changes() ([]Change, error) {
+	var changes []Change
+	for _, file := range []struct {
+		name     string
+		original []byte
+		current  *inifile.File
+	}{
+		{ConfigFile, s.configData, s.config},
+		{CredentialsFile, s.credentialsData, s.credentials},
+	} {
+		before, err := inifile.Parse(file.original)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, diffSections(file.name, before, file.current)...)
+	}
+	return changes, nil
+}
+
+func diffSections(file string, before, after *inifile.File) []Change {
+	old := sectionMap(before)
+	cur := sectionMap(after)
+
+	var changes []Change
+	for name, keys := range cur {
+		prev, ok := old[name]
+		switch {
+		case !ok:
+			changes = append(changes, Change{file, name, "added"})
+		case !equalKeys(prev, keys):
+			changes = append(changes, Change{file, name, "modified"})
+		}
+	}
+	for name := range old {
+		if _, ok := cur[name]; !ok {
+			changes = append(changes, Change{file, name, "removed"})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Section < changes[j].Section
+	})
+	return changes
+}
+
+func sectionMap(f *inifile.File) map[string]map[string]string {
+	m := make(map[string]map[string]string)
+	for _, section := range f.Sections() {
+		m[section.Name()] = section.Map()
+	}
+	return m
+}
+
+func equalKeys(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if bv, ok := b[k]; !ok || bv != v {
+			return false
+		}
+	}
+	return true
+}
+
+// Original returns the file contents as they were when the store was loaded.
+func (s *Store) Original() (config, credentials []byte) {
+	return s.configData, s.credentialsData
+}
+
+// Replace discards the in-memory state and replaces both files with the
+// given contents on the next Save.
+func (s *Store) Replace(config, credentials []byte) error {
+	cfg, err := inifile.Parse(config)
+	if err != nil {
+		return fmt.Errorf("parse config: %w", err)
+	}
+	creds, err := inifile.Parse(credentials)
+	if err != nil {
+		return fmt.Errorf("parse credentials: %w", err)
+	}
+	s.config = cfg
+	s.credentials = creds
+	return nil
+}
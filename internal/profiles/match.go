@@ -0,0 +1,103 @@
+package profiles
+
+import (
+	"sort"
+	"strings"
+)
+
+// Match ranks, from best to worst.
+const (
+	matchNone = iota
+	matchSubsequence
+	matchContains
+	matchPrefix
+	matchExact
+)
+
+// Match finds the profiles a query that is not exactly a profile name could
+// mean. The query is compared case-insensitively with each profile's name,
+// aliases and account ID, and only the best kind of match is kept: exact
+// aliases or account IDs first, then names or aliases containing the query,
+// then ones containing its letters in order ("pa" for "prod-admin").
+// Profiles are returned best first. sure reports whether the query
+// identifies a single profile well enough to act on without asking: an
+// exact match, or the only profile it is a prefix of.
+func (s *Store) Match(query string) (names []string, sure bool) {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return nil, false
+	}
+
+	type match struct {
+		name string
+		rank int
+	}
+	var matches []match
+	for _, p := range s.Profiles() {
+		if rank := p.match(query); rank != matchNone {
+			matches = append(matches, match{p.Name, rank})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].rank > matches[j].rank
+	})
+
+	for _, m := range matches {
+		// Prefix and substring matches are offered together, so "prod"
+		// doesn't silently pick prod-admin when nonprod exists too.
+		if tier(m.rank) != tier(matches[0].rank) {
+			break
+		}
+		names = append(names, m.name)
+	}
+	if len(names) == 0 {
+		return nil, false
+	}
+	sure = len(names) == 1 && (matches[0].rank == matchExact || matches[0].rank == matchPrefix)
+	return names, sure
+}
+
+func tier(rank int) int {
+	if rank == matchPrefix {
+		return matchContains
+	}
+	return rank
+}
+
+// match returns how well a lowercase query matches the profile.
+func (p *Profile) match(query string) int {
+	best := matchNone
+	for _, field := range append([]string{p.Name, p.AccountID()}, p.Aliases...) {
+		if rank := matchField(strings.ToLower(field), query); rank > best {
+			best = rank
+		}
+	}
+	return best
+}
+
+func matchField(field, query string) int {
+	switch {
+	case field == "":
+		return matchNone
+	case field == query:
+		return matchExact
+	case strings.HasPrefix(field, query):
+		return matchPrefix
+	case strings.Contains(field, query):
+		return matchContains
+	case isSubsequence(field, query):
+		return matchSubsequence
+	}
+	return matchNone
+}
+
+// isSubsequence reports whether the runes of query appear in s in order.
+func isSubsequence(s, query string) bool {
+	rest := []rune(query)
+	for _, r := range s {
+		if len(rest) > 0 && r == rest[0] {
+			rest = rest[1:]
+		}
+	}
+	return len(rest) == 0
+}
@@ -0,0 +1,76 @@
+package profiles
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// testStore writes config and credentials to a temporary directory and
+// loads them.
+func testStore(t *testing.T, config, credentials string) *Store {
+	t.Helper()
+	dir := t.TempDir()
+	paths := Paths{Config: filepath.Join(dir, "config"), Credentials: filepath.Join(dir, "credentials")}
+	if err := os.WriteFile(paths.Config, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(paths.Credentials, []byte(credentials), 0600); err != nil {
+		t.Fatal(err)
+	}
+	s, err := Load(paths)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+const matchConfig = `[profile prod-admin]
+sso_account_id = 111111111111
+sso_role_name = Admin
+gsd_aliases = p, live
+
+[profile prod-readonly]
+sso_account_id = 111111111111
+sso_role_name = ReadOnly
+
+[profile nonprod]
+sso_account_id = 222222222222
+sso_role_name = Admin
+
+[profile dev]
+role_arn = arn:aws:iam::333333333333:role/Dev
+source_profile = nonprod
+gsd_aliases = sandbox
+`
+
+func TestMatch(t *testing.T) {
+	s := testStore(t, matchConfig, "")
+	cases := []struct {
+		query string
+		want  []string
+		sure  bool
+	}{
+		{"p", []string{"prod-admin"}, true},                              // exact alias beats prefixes
+		{"LIVE", []string{"prod-admin"}, true},                           // case-insensitive
+		{"333333333333", []string{"dev"}, true},                          // account ID from the role ARN
+		{"111111111111", []string{"prod-admin", "prod-readonly"}, false}, // shared account ID
+		{"sand", []string{"dev"}, true},                                  // unique alias prefix
+		{"de", []string{"dev"}, true},                                    // unique name prefix
+		{"prod-r", []string{"prod-readonly"}, true},
+		{"prod", []string{"prod-admin", "prod-readonly", "nonprod"}, false}, // prefix and substring together, prefixes first
+		{"readonly", []string{"prod-readonly"}, false},                      // substring only
+		{"pdm", []string{"prod-admin"}, false},                              // letters in order
+		{"zz", nil, false},
+		{"", nil, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.query, func(t *testing.T) {
+			got, sure := s.Match(tc.query)
+			if !slices.Equal(got, tc.want) || sure != tc.sure {
+				t.Errorf("Match(%q) = %v, %v; want %v, %v", tc.query, got, sure, tc.want, tc.sure)
+			}
+		})
+	}
+}
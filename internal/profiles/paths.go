@@ -0,0 +1,62 @@
+package profiles
+
+import (
+	"os"
+	"os/user"
+	"path/filepath"
+)
+
+// Paths locates the AWS shared config and credentials files.
+type Paths struct {
+	Config      string
+	Credentials string
+}
+
+// ResolvePaths works out which files to use. Explicit paths win, then the
+// AWS_CONFIG_FILE and AWS_SHARED_CREDENTIALS_FILE environment variables,
+// then the defaults under ~/.aws, matching the AWS CLI and SDKs.
+func ResolvePaths(configFile, credentialsFile string) Paths {
+	if configFile == "" {
+		configFile = os.Getenv("AWS_CONFIG_FILE")
+	}
+	if configFile == "" {
+		configFile = filepath.Join(AWSDir(), "config")
+	}
+	if credentialsFile == "" {
+		credentialsFile = os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	}
+	if credentialsFile == "" {
+		credentialsFile = filepath.Join(AWSDir(), "credentials")
+	}
+	return Paths{
+		Config:      expandHome(configFile),
+		Credentials: expandHome(credentialsFile),
+	}
+}
+
+// HomeDir returns the current user's home directory, falling back to the
+// password database when HOME (or USERPROFILE on Windows) is unset.
+func HomeDir() string {
+	if home, err := os.UserHomeDir(); err == nil && home != "" {
+		return home
+	}
+	if u, err := user.Current(); err == nil && u.HomeDir != "" {
+		return u.HomeDir
+	}
+	return "."
+}
+
+// AWSDir returns the ~/.aws directory.
+func AWSDir() string {
+	return filepath.Join(HomeDir(), ".aws")
+}
+
+func expandHome(path string) string {
+	if path == "~" {
+		return HomeDir()
+	}
+	if len(path) > 1 && path[0] == '~' && (path[1] == '/' || path[1] == filepath.Separator) {
+		return filepath.Join(HomeDir(), path[2:])
+	}
+	return path
+}
@@ -0,0 +1,151 @@
+// Package profiles reads and writes the AWS shared config and credentials
+// files as a single merged set of named profiles.
+package profiles
+
+import "strings"
+
+// Type describes how a profile obtains credentials.
+type Type string
+
+const (
+	TypeSSO               Type = "sso"
+	TypeAccessKeys        Type = "access-keys"
+	TypeAssumeRole        Type = "assume-role"
+	TypeCredentialSource  Type = "credential-source"
+	TypeWebIdentity       Type = "web-identity"
+	TypeCredentialProcess Type = "credential-process"
+	TypeUnknown           Type = "unknown"
+)
+
+// Types lists every profile type, in the order used for display.
+var Types = []Type{
+	TypeSSO,
+	TypeAccessKeys,
+	TypeAssumeRole,
+	TypeCredentialSource,
+	TypeWebIdentity,
+	TypeCredentialProcess,
+	TypeUnknown,
+}
+
+// Label returns the human readable name used in prompts and listings.
+func (t Type) Label() string {
+	switch t {
+	case TypeSSO:
+		return "AWS SSO"
+	case TypeAccessKeys:
+		return "Access Keys"
+	case TypeAssumeRole:
+		return "Assume Role"
+	case TypeCredentialSource:
+		return "Credential Source Role"
+	case TypeWebIdentity:
+		return "Web Identity"
+	case TypeCredentialProcess:
+		return "Credential Process"
+	default:
+		return "Unknown"
+	}
+}
+
+// IsRole reports whether the profile assumes an IAM role via role_arn.
+func (t Type) IsRole() bool {
+	return t == TypeAssumeRole || t == TypeCredentialSource || t == TypeWebIdentity
+}
+
+// Source records which of the two AWS files define a profile.
+type Source uint8
+
+const (
+	SourceConfig Source = 1 << iota
+	SourceCredentials
+)
+
+func (s Source) String() string {
+	var parts []string
+	if s&SourceConfig != 0 {
+		parts = append(parts, "config")
+	}
+	if s&SourceCredentials != 0 {
+		parts = append(parts, "credentials")
+	}
+	return strings.Join(parts, "+")
+}
+
+// Profile is the merged view of a profile across the config and credentials files.
+type Profile struct {
+	Name   string
+	Type   Type
+	Region string
+	Source Source
+
+	// SSO settings, either inline (legacy) or through an sso-session block.
+	SSOSession   string
+	SSOStartURL  string
+	SSORegion    string
+	SSOAccountID string
+	SSORoleName  string
+
+	// Role assumption and chaining.
+	RoleARN              string
+	SourceProfile        string
+	CredentialSource     string
+	WebIdentityTokenFile string
+	MFASerial            string
+	ExternalID           string
+	RoleSessionName      string
+	DurationSeconds      string
+
+	CredentialProcess string
+	AccessKeyID       string
+
+	// Aliases and Tags are the lists from gsd_aliases and gsd_tags.
+	Aliases []string
+	Tags    []string
+}
+
+// classify works out the profile type from the fields that were loaded,
+// following the precedence the AWS SDKs use when several are present.
+func (p *Profile) classify() Type {
+	switch {
+	case p.RoleARN != "" && p.WebIdentityTokenFile != "":
+		return TypeWebIdentity
+	case p.RoleARN != "" && p.SourceProfile != "":
+		return TypeAssumeRole
+	case p.RoleARN != "" && p.CredentialSource != "":
+		return TypeCredentialSource
+	case p.SSOSession != "" || p.SSOStartURL != "":
+		return TypeSSO
+	case p.CredentialProcess != "":
+		return TypeCredentialProcess
+	case p.AccessKeyID != "":
+		return TypeAccessKeys
+	default:
+		return TypeUnknown
+	}
+}
+
+// AccountID returns the AWS account the profile targets, taken from the SSO
+// settings or the role ARN. It is empty when the config doesn't say.
+func (p *Profile) AccountID() string {
+	if p.SSOAccountID != "" {
+		return p.SSOAccountID
+	}
+	// arn:partition:iam::ACCOUNT:role/NAME
+	if parts := strings.SplitN(p.RoleARN, ":", 6); len(parts) == 6 {
+		return parts[4]
+	}
+	return ""
+}
+
+// RoleName returns the SSO permission set or the name of the assumed role.
+func (p *Profile) RoleName() string {
+	if p.SSORoleName != "" {
+		return p.SSORoleName
+	}
+	if i := strings.Index(p.RoleARN, ":role/"); i >= 0 {
+		name := p.RoleARN[i+len(":role/"):]
+		return name[strings.LastIndex(name, "/")+1:]
+	}
+	return ""
+}
@@ -0,0 +1,224 @@
+package profiles
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aphexlog/gsd/internal/inifile"
+)
+
+// SectionKind is a kind of config file section whose settings can be read
+// and changed one key at a time.
+type SectionKind string
+
+const (
+	ProfileSection    SectionKind = "profile"
+	SSOSessionSection SectionKind = "sso-session"
+	ServicesSection   SectionKind = "services"
+)
+
+const servicesPrefix = "services "
+
+// ManagedByKey tags the sections `gsd config sync` owns with the name of the
+// manifest that describes them. AWS tools ignore keys they don't know.
+const ManagedByKey = "gsd_managed_by"
+
+// AliasesKey lists other names a profile can be switched to by, separated
+// by commas or spaces, e.g. gsd_aliases = prod, p.
+const AliasesKey = "gsd_aliases"
+
+// TagsKey labels a profile for grouping in pickers and coloring the prompt
+// segment, e.g. gsd_tags = prod, billing.
+const TagsKey = "gsd_tags"
+
+// credentialKeys are the settings AWS tools read from the credentials file.
+var credentialKeys = map[string]bool{
+	"aws_access_key_id":     true,
+	"aws_secret_access_key": true,
+	"aws_session_token":     true,
+}
+
+// IsCredentialKey reports whether key belongs in the credentials file.
+func IsCredentialKey(key string) bool {
+	return credentialKeys[key]
+}
+
+// Target names a profile, sso-session or services section.
+type Target struct {
+	Kind SectionKind
+	Name string
+}
+
+// String returns the target as its config file header, e.g. [profile dev].
+func (t Target) String() string {
+	return "[" + t.configSection() + "]"
+}
+
+func (t Target) configSection() string {
+	switch t.Kind {
+	case SSOSessionSection:
+		return ssoSessionPrefix + t.Name
+	case ServicesSection:
+		return servicesPrefix + t.Name
+	default:
+		return configSectionName(t.Name)
+	}
+}
+
+// file returns the file and section that hold key for this target.
+// Nested keys are written as parent.key, e.g. s3.max_queue_size.
+func (s *Store) file(t Target, key string) (*inifile.File, string, string) {
+	if t.Kind == ProfileSection && IsCredentialKey(key) {
+		return s.credentials, t.Name, CredentialsFile
+	}
+	return s.config, t.configSection(), ConfigFile
+}
+
+// Exists reports whether the target's section is present in either file.
+func (s *Store) Exists(t Target) bool {
+	if t.Kind == ProfileSection {
+		return s.Has(t.Name)
+	}
+	return s.config.HasSection(t.configSection())
+}
+
+// Setting returns a single key of the target and the file it was found in.
+func (s *Store) Setting(t Target, key string) (value, file string, ok bool) {
+	f, section, file := s.file(t, key)
+	sec := f.Section(section)
+	parent, sub, nested := strings.Cut(key, ".")
+	if nested {
+		value, ok = sec.SubValues(parent)[sub]
+		return value, file, ok
+	}
+	return sec.Value(key), file, sec.Has(key)
+}
+
+// SetSetting assigns a single key of the target, creating its section if
+// needed, and returns the file it was written to.
+func (s *Store) SetSetting(t Target, key, value string) (string, error) {
+	if IsCredentialKey(key) && t.Kind != ProfileSection {
+		return "", fmt.Errorf("%s can only be set on a profile", key)
+	}
+	f, section, file := s.file(t, key)
+	sec := f.EnsureSection(section)
+	if parent, sub, nested := strings.Cut(key, "."); nested {
+		sec.SetSub(parent, sub, value)
+	} else {
+		sec.Set(key, value)
+	}
+	return file, nil
+}
+
+// UnsetSetting removes a single key of the target. It reports the file the
+// key was removed from, or false if it was not set.
+func (s *Store) UnsetSetting(t Target, key string) (string, bool) {
+	f, section, file := s.file(t, key)
+	sec := f.Section(section)
+	if sec == nil {
+		return "", false
+	}
+	if parent, sub, nested := strings.Cut(key, "."); nested {
+		return file, sec.DeleteSub(parent, sub)
+	}
+	return file, sec.Delete(key)
+}
+
+// Settings returns every key of the target's config section, with nested
+// settings flattened to parent.key.
+func (s *Store) Settings(t Target) map[string]string {
+	return flatten(s.config.Section(t.configSection()))
+}
+
+// Credentials returns every key of a profile's credentials section.
+func (s *Store) Credentials(name string) map[string]string {
+	return flatten(s.credentials.Section(name))
+}
+
+// ReplaceSettings makes the target's config section hold exactly settings.
+func (s *Store) ReplaceSettings(t Target, settings map[string]string) {
+	replace(s.config.EnsureSection(t.configSection()), settings)
+}
+
+// userMetadataKeys are the gsd keys that belong to the user even in a
+// section something else writes, such as a manifest.
+var userMetadataKeys = []string{AliasesKey, TagsKey}
+
+// KeepUserMetadata returns settings with the user's aliases and tags from
+// the existing target added, unless settings has its own.
+func (s *Store) KeepUserMetadata(t Target, settings map[string]string) map[string]string {
+	section := s.config.Section(t.configSection())
+	merged := make(map[string]string, len(settings))
+	for key, value := range settings {
+		merged[key] = value
+	}
+	for _, key := range userMetadataKeys {
+		if _, ok := merged[key]; !ok && section.Has(key) {
+			merged[key] = section.Value(key)
+		}
+	}
+	return merged
+}
+
+// ReplaceCredentials makes a profile's credentials section hold exactly
+// settings.
+func (s *Store) ReplaceCredentials(name string, settings map[string]string) {
+	replace(s.credentials.EnsureSection(name), settings)
+}
+
+// ServicesNames returns the names of every services section, sorted.
+func (s *Store) ServicesNames() []string {
+	var names []string
+	for _, section := range s.config.Sections() {
+		if name, ok := strings.CutPrefix(section.Name(), servicesPrefix); ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func flatten(sec *inifile.Section) map[string]string {
+	settings := make(map[string]string)
+	if sec == nil {
+		return settings
+	}
+	for _, key := range sec.Keys() {
+		if subs := sec.SubValues(key); len(subs) > 0 {
+			for sub, value := range subs {
+				settings[key+"."+sub] = value
+			}
+			continue
+		}
+		settings[key] = sec.Value(key)
+	}
+	return settings
+}
+
+func replace(sec *inifile.Section, settings map[string]string) {
+	for _, key := range sec.Keys() {
+		sec.Delete(key)
+	}
+	for _, key := range sortedKeys(settings) {
+		if parent, sub, nested := strings.Cut(key, "."); nested {
+			sec.SetSub(parent, sub, settings[key])
+		} else {
+			sec.Set(key, settings[key])
+		}
+	}
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ManagedBy returns the manifest that manages the target, or "".
+func (s *Store) ManagedBy(t Target) string {
+	return s.config.Section(t.configSection()).Value(ManagedByKey)
+}
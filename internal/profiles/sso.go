@@ -0,0 +1,89 @@
+package profiles
+
+import (
+	"sort"
+	"strings"
+)
+
+// DefaultRegistrationScopes is what the AWS CLI suggests for new sessions.
+const DefaultRegistrationScopes = "sso:account:access"
+
+// SSOSession is an [sso-session NAME] block shared by SSO profiles.
+type SSOSession struct {
+	Name               string
+	StartURL           string
+	Region             string
+	RegistrationScopes string
+}
+
+// SSOSessions returns every sso-session block, sorted by name.
+func (s *Store) SSOSessions() []*SSOSession {
+	var sessions []*SSOSession
+	for _, section := range s.config.Sections() {
+		if name, ok := strings.CutPrefix(section.Name(), ssoSessionPrefix); ok {
+			sess, _ := s.GetSSOSession(name)
+			sessions = append(sessions, sess)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].Name < sessions[j].Name
+	})
+	return sessions
+}
+
+// SSOSessionNames returns the names of every sso-session block, sorted.
+func (s *Store) SSOSessionNames() []string {
+	var names []string
+	for _, sess := range s.SSOSessions() {
+		names = append(names, sess.Name)
+	}
+	return names
+}
+
+// GetSSOSession returns a single sso-session block.
+func (s *Store) GetSSOSession(name string) (*SSOSession, bool) {
+	section := s.config.Section(ssoSessionPrefix + name)
+	if section == nil {
+		return nil, false
+	}
+	return &SSOSession{
+		Name:               name,
+		StartURL:           section.Value("sso_start_url"),
+		Region:             section.Value("sso_region"),
+		RegistrationScopes: section.Value("sso_registration_scopes"),
+	}, true
+}
+
+// PutSSOSession creates or updates an sso-session block.
+func (s *Store) PutSSOSession(sess *SSOSession) {
+	section := s.config.EnsureSection(ssoSessionPrefix + sess.Name)
+	section.Set("sso_start_url", sess.StartURL)
+	section.Set("sso_region", sess.Region)
+	if sess.RegistrationScopes != "" {
+		section.Set("sso_registration_scopes", sess.RegistrationScopes)
+	} else {
+		section.Delete("sso_registration_scopes")
+	}
+}
+
+// SSOSessionValue returns a key from an [sso-session NAME] block.
+func (s *Store) SSOSessionValue(session, key string) string {
+	return s.config.Section(ssoSessionPrefix + session).Value(key)
+}
+
+// ProfilesUsingSSOSession returns the profiles whose sso_session is name.
+func (s *Store) ProfilesUsingSSOSession(name string) []string {
+	var users []string
+	for _, p := range s.Profiles() {
+		if p.SSOSession == name {
+			users = append(users, p.Name)
+		}
+	}
+	return users
+}
+
+// RemoveSSOSession deletes an sso-session block. Profiles that reference it
+// are left alone; callers decide whether to refuse or cascade.
+func (s *Store) RemoveSSOSession(name string) {
+	s.config.DeleteSection(ssoSessionPrefix + name)
+}
@@ -0,0 +1,339 @@
+package profiles
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/aphexlog/gsd/internal/fsutil"
+	"github.com/aphexlog/gsd/internal/inifile"
+)
+
+const (
+	// DefaultProfile is the profile the AWS tools fall back to.
+	DefaultProfile = "default"
+
+	// ConfigFile and CredentialsFile name the two files in a Change or a
+	// Setting.
+	ConfigFile      = "config"
+	CredentialsFile = "credentials"
+
+	profilePrefix    = "profile "
+	ssoSessionPrefix = "sso-session "
+)
+
+// Store holds the AWS config and credentials files in memory. All reads and
+// writes of profiles go through a Store so every command sees the same set.
+type Store struct {
+	Paths Paths
+
+	config      *inifile.File
+	credentials *inifile.File
+
+	// Contents as loaded, used to skip untouched files and to roll back.
+	configData      []byte
+	credentialsData []byte
+}
+
+// Load reads both files. Missing files are treated as empty.
+func Load(paths Paths) (*Store, error) {
+	s := &Store{Paths: paths}
+
+	var err error
+	if s.configData, err = fsutil.ReadFileIfExists(paths.Config); err != nil {
+		return nil, err
+	}
+	if s.credentialsData, err = fsutil.ReadFileIfExists(paths.Credentials); err != nil {
+		return nil, err
+	}
+	if s.config, err = inifile.Parse(s.configData); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", paths.Config, err)
+	}
+	if s.credentials, err = inifile.Parse(s.credentialsData); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", paths.Credentials, err)
+	}
+	return s, nil
+}
+
+// Update locks the AWS files, loads them, applies fn and saves the result.
+// Interactive commands should prompt first and only call Update to apply the
+// answers, so the lock is never held while waiting on a user.
+func Update(paths Paths, fn func(*Store) error) error {
+	lock, err := fsutil.AcquireLock(lockPath(paths))
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	s, err := Load(paths)
+	if err != nil {
+		return err
+	}
+	if err := fn(s); err != nil {
+		return err
+	}
+	return s.Save()
+}
+
+// lockPath is the advisory lock file guarding both AWS files.
+func lockPath(paths Paths) string {
+	return paths.Config + ".gsd.lock"
+}
+
+// Save writes any changed file back to disk. Each file is replaced
+// atomically; if the credentials file cannot be written after the config
+// file was, the config file is restored so the pair never goes out of sync.
+func (s *Store) Save() error {
+	configData := s.config.Bytes()
+	credentialsData := s.credentials.Bytes()
+
+	configChanged := !bytes.Equal(configData, s.configData)
+	credentialsChanged := !bytes.Equal(credentialsData, s.credentialsData)
+
+	if configChanged {
+		if err := fsutil.WriteFileAtomic(s.Paths.Config, configData, 0600); err != nil {
+			return fmt.Errorf("write config file: %w", err)
+		}
+	}
+	if credentialsChanged {
+		if err := fsutil.WriteFileAtomic(s.Paths.Credentials, credentialsData, 0600); err != nil {
+			if configChanged {
+				if rbErr := fsutil.WriteFileAtomic(s.Paths.Config, s.configData, 0600); rbErr != nil {
+					return fmt.Errorf("write credentials file: %w (restoring config file also failed: %v)", err, rbErr)
+				}
+			}
+			return fmt.Errorf("write credentials file: %w", err)
+		}
+	}
+
+	s.configData = configData
+	s.credentialsData = credentialsData
+	return nil
+}
+
+// configSectionName maps a profile name to its section in the config file.
+func configSectionName(name string) string {
+	if name == DefaultProfile {
+		return DefaultProfile
+	}
+	return profilePrefix + name
+}
+
+// ValidateName rejects names that can't be written as a section header.
+func ValidateName(name string) error {
+	if name == "" || strings.TrimSpace(name) != name || strings.ContainsAny(name, "[]\r\n") {
+		return fmt.Errorf("'%s' is not a valid profile name", name)
+	}
+	return nil
+}
+
+// profileName maps a config file section back to a profile name. Sections
+// that are not profiles (sso-session, services, ...) report false.
+func profileName(section string) (string, bool) {
+	if section == DefaultProfile {
+		return DefaultProfile, true
+	}
+	if strings.HasPrefix(section, profilePrefix) {
+		return strings.TrimSpace(strings.TrimPrefix(section, profilePrefix)), true
+	}
+	return "", false
+}
+
+// Names returns every profile name defined in either file, sorted.
+func (s *Store) Names() []string {
+	seen := make(map[string]bool)
+	for _, section := range s.config.Sections() {
+		if name, ok := profileName(section.Name()); ok {
+			seen[name] = true
+		}
+	}
+	for _, section := range s.credentials.Sections() {
+		seen[section.Name()] = true
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Has reports whether a profile exists in either file.
+func (s *Store) Has(name string) bool {
+	return s.config.HasSection(configSectionName(name)) || s.credentials.HasSection(name)
+}
+
+// Profiles returns the merged view of every profile, sorted by name.
+func (s *Store) Profiles() []*Profile {
+	names := s.Names()
+	list := make([]*Profile, 0, len(names))
+	for _, name := range names {
+		p, _ := s.Get(name)
+		list = append(list, p)
+	}
+	return list
+}
+
+// Get returns the merged view of a single profile.
+func (s *Store) Get(name string) (*Profile, bool) {
+	if !s.Has(name) {
+		return nil, false
+	}
+
+	p := &Profile{Name: name}
+	if s.config.HasSection(configSectionName(name)) {
+		p.Source |= SourceConfig
+	}
+	if s.credentials.HasSection(name) {
+		p.Source |= SourceCredentials
+	}
+
+	p.Region = s.ConfigValue(name, "region")
+	p.SSOSession = s.ConfigValue(name, "sso_session")
+	p.SSOStartURL = s.ConfigValue(name, "sso_start_url")
+	p.SSORegion = s.ConfigValue(name, "sso_region")
+	p.SSOAccountID = s.ConfigValue(name, "sso_account_id")
+	p.SSORoleName = s.ConfigValue(name, "sso_role_name")
+	p.RoleARN = s.ConfigValue(name, "role_arn")
+	p.SourceProfile = s.ConfigValue(name, "source_profile")
+	p.CredentialSource = s.ConfigValue(name, "credential_source")
+	p.WebIdentityTokenFile = s.ConfigValue(name, "web_identity_token_file")
+	p.MFASerial = s.ConfigValue(name, "mfa_serial")
+	p.ExternalID = s.ConfigValue(name, "external_id")
+	p.RoleSessionName = s.ConfigValue(name, "role_session_name")
+	p.DurationSeconds = s.ConfigValue(name, "duration_seconds")
+	p.CredentialProcess = s.ConfigValue(name, "credential_process")
+	p.Aliases = splitList(s.ConfigValue(name, AliasesKey))
+	p.Tags = splitList(s.ConfigValue(name, TagsKey))
+
+	// Static keys may live in either file; the credentials file wins.
+	p.AccessKeyID = s.CredentialValue(name, "aws_access_key_id")
+	if p.AccessKeyID == "" {
+		p.AccessKeyID = s.ConfigValue(name, "aws_access_key_id")
+	}
+
+	// Profiles using an sso-session block inherit its start URL and region.
+	if p.SSOSession != "" {
+		if v := s.SSOSessionValue(p.SSOSession, "sso_start_url"); v != "" {
+			p.SSOStartURL = v
+		}
+		if v := s.SSOSessionValue(p.SSOSession, "sso_region"); v != "" {
+			p.SSORegion = v
+		}
+	}
+
+	p.Type = p.classify()
+	return p, true
+}
+
+// splitList splits a setting holding a list separated by commas or spaces.
+func splitList(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+}
+
+// ConfigValue returns a key from the profile's config section.
+func (s *Store) ConfigValue(name, key string) string {
+	return s.config.Section(configSectionName(name)).Value(key)
+}
+
+// SetConfig sets a key in the profile's config section, creating it if needed.
+func (s *Store) SetConfig(name, key, value string) {
+	s.config.EnsureSection(configSectionName(name)).Set(key, value)
+}
+
+// DeleteConfigKey removes a key from the profile's config section.
+func (s *Store) DeleteConfigKey(name, key string) {
+	if section := s.config.Section(configSectionName(name)); section != nil {
+		section.Delete(key)
+	}
+}
+
+// CredentialValue returns a key from the profile's credentials section.
+func (s *Store) CredentialValue(name, key string) string {
+	return s.credentials.Section(name).Value(key)
+}
+
+// SetCredential sets a key in the profile's credentials section.
+func (s *Store) SetCredential(name, key, value string) {
+	s.credentials.EnsureSection(name).Set(key, value)
+}
+
+// HasCredentials reports whether the profile has a credentials section.
+func (s *Store) HasCredentials(name string) bool {
+	return s.credentials.HasSection(name)
+}
+
+// Remove deletes the profile from both files.
+func (s *Store) Remove(name string) {
+	s.config.DeleteSection(configSectionName(name))
+	s.credentials.DeleteSection(name)
+}
+
+// CopyToDefault replaces the default profile with the contents of name in
+// both files.
+func (s *Store) CopyToDefault(name string) error {
+	if name == DefaultProfile {
+		return nil
+	}
+	if !s.Has(name) {
+		return fmt.Errorf("profile '%s' not found", name)
+	}
+
+	s.config.EnsureSection(DefaultProfile).ReplaceKeys(s.config.Section(configSectionName(name)))
+	if src := s.credentials.Section(name); src != nil {
+		s.credentials.EnsureSection(DefaultProfile).ReplaceKeys(src)
+	}
+	return nil
+}
+
+// Rename moves a profile to a new name in both files and points every
+// source_profile that named it at the new name. It returns the profiles
+// whose source_profile was rewritten.
+func (s *Store) Rename(from, to string) ([]string, error) {
+	if !s.Has(from) {
+		return nil, fmt.Errorf("profile '%s' not found", from)
+	}
+	if s.Has(to) {
+		return nil, fmt.Errorf("profile '%s' already exists", to)
+	}
+
+	s.config.RenameSection(configSectionName(from), configSectionName(to))
+	s.credentials.RenameSection(from, to)
+
+	var updated []string
+	for _, name := range s.Names() {
+		section := s.config.Section(configSectionName(name))
+		if section.Value("source_profile") == from {
+			section.Set("source_profile", to)
+			updated = append(updated, name)
+		}
+	}
+	return updated, nil
+}
+
+// Clone copies a profile to a new name in both files. The copy is the
+// user's own, so it doesn't keep the tag of the manifest that manages the
+// original.
+func (s *Store) Clone(from, to string) error {
+	if !s.Has(from) {
+		return fmt.Errorf("profile '%s' not found", from)
+	}
+	if s.Has(to) {
+		return fmt.Errorf("profile '%s' already exists", to)
+	}
+
+	if src := s.config.Section(configSectionName(from)); src != nil {
+		dst := s.config.EnsureSection(configSectionName(to))
+		dst.ReplaceKeys(src)
+		dst.Delete(ManagedByKey)
+	}
+	if src := s.credentials.Section(from); src != nil {
+		s.credentials.EnsureSection(to).ReplaceKeys(src)
+	}
+	return nil
+}
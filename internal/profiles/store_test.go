@@ -0,0 +1,162 @@
+package profiles
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/aphexlog/gsd/internal/fsutil"
+)
+
+const storeConfig = `[profile dev]
+region = us-east-1
+
+[profile admin]
+role_arn = arn:aws:iam::111111111111:role/Admin
+source_profile = dev
+
+[profile managed]
+region = eu-west-1
+gsd_managed_by = team
+`
+
+const storeCredentials = `[dev]
+aws_access_key_id = AKIA1
+aws_secret_access_key = secret
+`
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestSaveRollsBackConfig(t *testing.T) {
+	s := testStore(t, storeConfig, storeCredentials)
+	s.SetConfig("dev", "region", "eu-west-1")
+	s.SetCredential("dev", "aws_secret_access_key", "rotated")
+
+	// A non-empty directory in place of the credentials file makes its
+	// write fail after the config file was already replaced.
+	if err := os.Remove(s.Paths.Credentials); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(s.Paths.Credentials, "keep"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Save(); err == nil {
+		t.Fatal("expected Save to fail")
+	}
+	if got := readFile(t, s.Paths.Config); got != storeConfig {
+		t.Errorf("config file was not restored:\n%s", got)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	cases := []struct {
+		name string
+		fn   func(*Store) error
+		want string
+	}{
+		{"saves the change", func(s *Store) error {
+			s.SetConfig("dev", "region", "eu-west-1")
+			return nil
+		}, "[profile dev]\nregion = eu-west-1\n"},
+		{"error writes nothing", func(s *Store) error {
+			s.SetConfig("dev", "region", "eu-west-1")
+			return errors.New("boom")
+		}, "[profile dev]\nregion = us-east-1\n"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := testStore(t, "[profile dev]\nregion = us-east-1\n", "")
+			Update(s.Paths, tc.fn)
+			if got := readFile(t, s.Paths.Config); got != tc.want {
+				t.Errorf("config file:\n%s\nwant:\n%s", got, tc.want)
+			}
+
+			// The lock is released either way.
+			lock, err := fsutil.AcquireLock(lockPath(s.Paths))
+			if err != nil {
+				t.Fatal(err)
+			}
+			lock.Release()
+		})
+	}
+}
+
+func TestRename(t *testing.T) {
+	cases := []struct {
+		from, to string
+		updated  []string
+		ok       bool
+	}{
+		{"dev", "sandbox", []string{"admin"}, true},
+		{"admin", "root", nil, true},
+		{"dev", "admin", nil, false},
+		{"nope", "other", nil, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.from+"->"+tc.to, func(t *testing.T) {
+			s := testStore(t, storeConfig, storeCredentials)
+			updated, err := s.Rename(tc.from, tc.to)
+			if (err == nil) != tc.ok {
+				t.Fatalf("Rename() = %v, want ok %v", err, tc.ok)
+			}
+			if !tc.ok {
+				return
+			}
+			if !slices.Equal(updated, tc.updated) {
+				t.Errorf("updated = %v, want %v", updated, tc.updated)
+			}
+			if s.Has(tc.from) || !s.Has(tc.to) {
+				t.Errorf("profiles after rename: %v", s.Names())
+			}
+			if s.ConfigValue("admin", "source_profile") == tc.from {
+				t.Error("source_profile still names the old profile")
+			}
+			if tc.from == "dev" && s.CredentialValue(tc.to, "aws_access_key_id") != "AKIA1" {
+				t.Error("credentials were not renamed")
+			}
+		})
+	}
+}
+
+func TestClone(t *testing.T) {
+	cases := []struct {
+		from, to string
+		want     map[string]string
+		ok       bool
+	}{
+		{"dev", "dev2", map[string]string{"region": "us-east-1", "aws_access_key_id": "AKIA1"}, true},
+		{"managed", "mine", map[string]string{"region": "eu-west-1", ManagedByKey: ""}, true},
+		{"dev", "admin", nil, false},
+		{"nope", "other", nil, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.from+"->"+tc.to, func(t *testing.T) {
+			s := testStore(t, storeConfig, storeCredentials)
+			if err := s.Clone(tc.from, tc.to); (err == nil) != tc.ok {
+				t.Fatalf("Clone() = %v, want ok %v", err, tc.ok)
+			}
+			for key, want := range tc.want {
+				got := s.ConfigValue(tc.to, key)
+				if key == "aws_access_key_id" {
+					got = s.CredentialValue(tc.to, key)
+				}
+				if got != want {
+					t.Errorf("%s = %q, want %q", key, got, want)
+				}
+			}
+			if tc.ok && !s.Has(tc.from) {
+				t.Error("clone removed the original")
+			}
+		})
+	}
+}
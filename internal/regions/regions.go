@@ -0,0 +1,154 @@
+// Package regions is gsd's catalog of AWS regions: an embedded list covering
+// every partition, extended with regions found by `gsd regions --live`.
+package regions
+
+import (
+	_ "embed"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aphexlog/gsd/internal/fsutil"
+	"github.com/aphexlog/gsd/internal/state"
+)
+
+// Partitions.
+const (
+	PartitionAWS   = "aws"
+	PartitionChina = "aws-cn"
+	PartitionGov   = "aws-us-gov"
+)
+
+// Region is one catalog entry.
+type Region struct {
+	Code      string `json:"code" yaml:"code"`
+	Name      string `json:"name,omitempty" yaml:"name,omitempty"`
+	Partition string `json:"partition" yaml:"partition"`
+	OptIn     bool   `json:"opt_in" yaml:"opt_in"`
+}
+
+//go:embed regions.json
+var embedded []byte
+
+func cachePath() string {
+	return filepath.Join(state.Dir(), "regions.json")
+}
+
+// catalog is read once per run: the embedded list plus the regions learned
+// from a live refresh, sorted by partition and code, and indexed by code.
+// Regions learned during the run only show up in the next one.
+var catalog = sync.OnceValue(func() regionCatalog {
+	byCode := make(map[string]Region)
+	var list []Region
+	_ = json.Unmarshal(embedded, &list)
+	for _, r := range list {
+		byCode[r.Code] = r
+	}
+
+	if data, err := fsutil.ReadFileIfExists(cachePath()); err == nil && data != nil {
+		var learned []Region
+		if json.Unmarshal(data, &learned) == nil {
+			for _, r := range learned {
+				if _, known := byCode[r.Code]; !known {
+					byCode[r.Code] = r
+				}
+			}
+		}
+	}
+
+	all := make([]Region, 0, len(byCode))
+	for _, r := range byCode {
+		all = append(all, r)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].Partition != all[j].Partition {
+			return all[i].Partition < all[j].Partition
+		}
+		return all[i].Code < all[j].Code
+	})
+	return regionCatalog{all: all, byCode: byCode}
+})
+
+type regionCatalog struct {
+	all    []Region
+	byCode map[string]Region
+}
+
+// All returns the catalog sorted by partition and code. Regions learned
+// from a live refresh are included alongside the embedded ones.
+func All() []Region {
+	return slices.Clone(catalog().all)
+}
+
+// Lookup returns the catalog entry for code.
+func Lookup(code string) (Region, bool) {
+	r, ok := catalog().byCode[code]
+	return r, ok
+}
+
+// Codes returns every region code in the catalog.
+func Codes() []string {
+	var codes []string
+	for _, r := range All() {
+		codes = append(codes, r.Code)
+	}
+	return codes
+}
+
+// PartitionOf guesses the partition of a region code from its prefix.
+func PartitionOf(code string) string {
+	switch {
+	case strings.HasPrefix(code, "cn-"):
+		return PartitionChina
+	case strings.HasPrefix(code, "us-gov-"):
+		return PartitionGov
+	default:
+		return PartitionAWS
+	}
+}
+
+// Learn remembers regions from a live listing that the embedded catalog
+// does not know yet, and returns how many were new.
+func Learn(live []Region) (int, error) {
+	known := make(map[string]bool)
+	for _, r := range All() {
+		known[r.Code] = true
+	}
+
+	var learned []Region
+	if data, err := fsutil.ReadFileIfExists(cachePath()); err == nil && data != nil {
+		_ = json.Unmarshal(data, &learned)
+	}
+	added := 0
+	for _, r := range live {
+		if !known[r.Code] {
+			learned = append(learned, r)
+			added++
+		}
+	}
+	if added == 0 {
+		return 0, nil
+	}
+
+	data, err := json.MarshalIndent(learned, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	if err := os.MkdirAll(state.Dir(), 0700); err != nil {
+		return 0, err
+	}
+	return added, fsutil.WriteFileAtomic(cachePath(), data, 0600)
+}
+
+// Matches reports whether r matches a search term, ignoring case, by code,
+// name or partition.
+func (r Region) Matches(term string) bool {
+	term = strings.ToLower(term)
+	return strings.Contains(strings.ToLower(r.Code), term) ||
+		strings.Contains(strings.ToLower(r.Name), term) ||
+		r.Partition == term
+}
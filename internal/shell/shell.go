@@ -0,0 +1,245 @@
+// Package shell generates the wrapper function that lets gsd change
+// environment variables in the shell it runs from, and the snippets that
+// wrapper evaluates.
+//
+// A process cannot change its parent's environment, so the wrapper runs gsd
+// with GSD_ENV_FILE pointing at a temporary file. gsd writes the variables
+// to set there, in the wrapper's own syntax, and the wrapper evaluates the
+// file once gsd exits.
+package shell
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Environment variables shared between gsd and the wrapper.
+const (
+	EnvFileVar      = "GSD_ENV_FILE"
+	ShellVar        = "GSD_SHELL"
+	ExportRegionVar = "GSD_EXPORT_REGION"
+
+	// PreviousVar holds the profile the shell used before its last switch,
+	// for `gsd switch -`.
+	PreviousVar = "GSD_PREVIOUS_PROFILE"
+
+	// PinVar holds the pin file whose profile is active, PinHashVar the
+	// hash of the content that was trusted, and RestoreVar the variables
+	// to put back when leaving its directory.
+	PinVar     = "GSD_PIN"
+	PinHashVar = "GSD_PIN_HASH"
+	RestoreVar = "GSD_PIN_RESTORE"
+)
+
+// Shells are the shells gsd can integrate with.
+var Shells = []string{"bash", "zsh", "fish", "powershell"}
+
+// Supported reports whether gsd has a wrapper for the named shell.
+func Supported(name string) bool {
+	for _, s := range Shells {
+		if s == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Init returns the wrapper function for a shell. With exportRegion the
+// wrapper asks gsd to export AWS_REGION alongside AWS_PROFILE. With hook it
+// also runs `gsd hook` whenever the working directory changes, which
+// activates the profile pinned for that directory.
+func Init(name string, exportRegion, hook bool) (string, error) {
+	region := "0"
+	if exportRegion {
+		region = "1"
+	}
+	var script string
+	switch name {
+	case "bash":
+		script = fmt.Sprintf(posixInit, name, region)
+		if hook {
+			script += bashHook
+		}
+	case "zsh":
+		script = fmt.Sprintf(posixInit, name, region)
+		if hook {
+			script += zshHook
+		}
+	case "fish":
+		script = fmt.Sprintf(fishInit, region)
+		if hook {
+			script += fishHook
+		}
+	case "powershell":
+		script = fmt.Sprintf(powershellInit, region)
+		if hook {
+			script += powershellHook
+		}
+	default:
+		return "", fmt.Errorf("unsupported shell '%s' (use %s)", name, strings.Join(Shells, ", "))
+	}
+	return script, nil
+}
+
+const posixInit = `# gsd shell integration: eval "$(gsd shell-init %[1]s)"
+gsd() {
+  local gsd_env gsd_status
+  gsd_env="$(mktemp "${TMPDIR:-/tmp}/gsd-env.XXXXXX")" || return
+  GSD_SHELL=%[1]s GSD_ENV_FILE="$gsd_env" GSD_EXPORT_REGION=%[2]s command gsd "$@"
+  gsd_status=$?
+  if [ -s "$gsd_env" ]; then
+    . "$gsd_env"
+  fi
+  rm -f "$gsd_env"
+  return $gsd_status
+}
+`
+
+const fishInit = `# gsd shell integration: gsd shell-init fish | source
+function gsd
+    set -l gsd_env (mktemp)
+    or return
+    GSD_SHELL=fish GSD_ENV_FILE=$gsd_env GSD_EXPORT_REGION=%s command gsd $argv
+    set -l gsd_status $status
+    if test -s $gsd_env
+        source $gsd_env
+    end
+    rm -f $gsd_env
+    return $gsd_status
+end
+`
+
+const powershellInit = `# gsd shell integration: gsd shell-init powershell | Out-String | Invoke-Expression
+function gsd {
+    $gsdEnv = [System.IO.Path]::GetTempFileName()
+    $gsdExe = Get-Command gsd -CommandType Application | Select-Object -First 1
+    $env:GSD_SHELL = 'powershell'
+    $env:GSD_ENV_FILE = $gsdEnv
+    $env:GSD_EXPORT_REGION = '%s'
+    try {
+        & $gsdExe @args
+        $gsdStatus = $LASTEXITCODE
+    } finally {
+        Remove-Item Env:GSD_SHELL, Env:GSD_ENV_FILE, Env:GSD_EXPORT_REGION -ErrorAction SilentlyContinue
+    }
+    if ((Get-Item $gsdEnv).Length -gt 0) {
+        Get-Content -Raw $gsdEnv | Invoke-Expression
+    }
+    Remove-Item $gsdEnv -ErrorAction SilentlyContinue
+    $global:LASTEXITCODE = $gsdStatus
+}
+`
+
+const bashHook = `
+_gsd_hook() {
+  local gsd_last=$?
+  if [ "$PWD" != "${_GSD_LAST_PWD-}" ]; then
+    _GSD_LAST_PWD="$PWD"
+    gsd hook
+  fi
+  return $gsd_last
+}
+case ";${PROMPT_COMMAND-};" in
+  *";_gsd_hook;"*) ;;
+  *) PROMPT_COMMAND="_gsd_hook${PROMPT_COMMAND:+;$PROMPT_COMMAND}" ;;
+esac
+`
+
+const zshHook = `
+_gsd_hook() {
+  gsd hook
+}
+autoload -Uz add-zsh-hook
+add-zsh-hook chpwd _gsd_hook
+_gsd_hook
+`
+
+const fishHook = `
+function __gsd_hook --on-variable PWD
+    gsd hook
+end
+__gsd_hook
+`
+
+const powershellHook = `
+$global:GsdLastPwd = $null
+$global:GsdOriginalPrompt = $function:prompt
+function global:prompt {
+    if ($PWD.Path -ne $global:GsdLastPwd) {
+        $global:GsdLastPwd = $PWD.Path
+        gsd hook
+    }
+    & $global:GsdOriginalPrompt
+}
+`
+
+// Active reports whether gsd is running under the wrapper.
+func Active() bool {
+	return os.Getenv(EnvFileVar) != "" && Supported(os.Getenv(ShellVar))
+}
+
+// ExportRegion reports whether the wrapper wants AWS_REGION exported.
+func ExportRegion() bool {
+	return os.Getenv(ExportRegionVar) == "1"
+}
+
+// Export asks the wrapper to set variables in the calling shell. An empty
+// value unsets the variable.
+func Export(vars map[string]string) error {
+	if !Active() {
+		return fmt.Errorf("shell integration is not active")
+	}
+	script := Script(os.Getenv(ShellVar), vars)
+
+	f, err := os.OpenFile(os.Getenv(EnvFileVar), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(script); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Script renders variable assignments in the syntax of a shell.
+func Script(name string, vars map[string]string) string {
+	keys := make([]string, 0, len(vars))
+	for key := range vars {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, key := range keys {
+		value := vars[key]
+		switch name {
+		case "fish":
+			if value == "" {
+				fmt.Fprintf(&b, "set -e %s\n", key)
+			} else {
+				fmt.Fprintf(&b, "set -gx %s %s\n", key, fishQuote(value))
+			}
+		case "powershell":
+			if value == "" {
+				fmt.Fprintf(&b, "Remove-Item Env:%s -ErrorAction SilentlyContinue\n", key)
+			} else {
+				fmt.Fprintf(&b, "$env:%s = '%s'\n", key, strings.ReplaceAll(value, "'", "''"))
+			}
+		default:
+			if value == "" {
+				fmt.Fprintf(&b, "unset %s\n", key)
+			} else {
+				fmt.Fprintf(&b, "export %s='%s'\n", key, strings.ReplaceAll(value, "'", `'\''`))
+			}
+		}
+	}
+	return b.String()
+}
+
+func fishQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return "'" + strings.ReplaceAll(s, "'", `\'`) + "'"
+}
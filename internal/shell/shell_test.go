@@ -0,0 +1,126 @@
+package shell
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScript(t *testing.T) {
+	cases := []struct {
+		name  string
+		value string
+		want  map[string]string
+	}{
+		{"plain", "dev", map[string]string{
+			"bash":       "export AWS_PROFILE='dev'\n",
+			"zsh":        "export AWS_PROFILE='dev'\n",
+			"fish":       "set -gx AWS_PROFILE 'dev'\n",
+			"powershell": "$env:AWS_PROFILE = 'dev'\n",
+		}},
+		{"single quote", "it's", map[string]string{
+			"bash":       `export AWS_PROFILE='it'\''s'` + "\n",
+			"zsh":        `export AWS_PROFILE='it'\''s'` + "\n",
+			"fish":       `set -gx AWS_PROFILE 'it\'s'` + "\n",
+			"powershell": "$env:AWS_PROFILE = 'it''s'\n",
+		}},
+		{"dollar and backslash", `$HOME\x`, map[string]string{
+			"bash":       `export AWS_PROFILE='$HOME\x'` + "\n",
+			"zsh":        `export AWS_PROFILE='$HOME\x'` + "\n",
+			"fish":       `set -gx AWS_PROFILE '$HOME\\x'` + "\n",
+			"powershell": `$env:AWS_PROFILE = '$HOME\x'` + "\n",
+		}},
+		{"newline", "a\nb", map[string]string{
+			"bash":       "export AWS_PROFILE='a\nb'\n",
+			"zsh":        "export AWS_PROFILE='a\nb'\n",
+			"fish":       "set -gx AWS_PROFILE 'a\nb'\n",
+			"powershell": "$env:AWS_PROFILE = 'a\nb'\n",
+		}},
+		{"empty unsets", "", map[string]string{
+			"bash":       "unset AWS_PROFILE\n",
+			"zsh":        "unset AWS_PROFILE\n",
+			"fish":       "set -e AWS_PROFILE\n",
+			"powershell": "Remove-Item Env:AWS_PROFILE -ErrorAction SilentlyContinue\n",
+		}},
+	}
+	for _, tc := range cases {
+		for _, sh := range Shells {
+			t.Run(tc.name+"/"+sh, func(t *testing.T) {
+				got := Script(sh, map[string]string{"AWS_PROFILE": tc.value})
+				if got != tc.want[sh] {
+					t.Errorf("Script(%s) = %q, want %q", sh, got, tc.want[sh])
+				}
+			})
+		}
+	}
+}
+
+func TestScriptSorted(t *testing.T) {
+	got := Script("bash", map[string]string{"B": "2", "A": "1", "C": ""})
+	want := "export A='1'\nexport B='2'\nunset C\n"
+	if got != want {
+		t.Errorf("Script() = %q, want %q", got, want)
+	}
+}
+
+// TestScriptRoundTrip evaluates the scripts in the real shells, where they
+// are installed, and checks the values come back unchanged.
+func TestScriptRoundTrip(t *testing.T) {
+	values := []string{"dev", "it's", `'"`, `$HOME`, "`id`", `a\b`, "a\nb", "a b  c", "ü"}
+	runners := map[string]func(script string) []string{
+		"bash": func(script string) []string { return []string{"bash", "-c", script + `printf '%s\0' "$V"`} },
+		"zsh":  func(script string) []string { return []string{"zsh", "-c", script + `printf '%s\0' "$V"`} },
+		"fish": func(script string) []string { return []string{"fish", "-c", script + `printf '%s\0' "$V"`} },
+		"powershell": func(script string) []string {
+			return []string{"pwsh", "-NoProfile", "-Command", script + `[Console]::Out.Write($env:V + [char]0)`}
+		},
+	}
+	for _, sh := range Shells {
+		t.Run(sh, func(t *testing.T) {
+			argv := runners[sh]("")
+			if _, err := exec.LookPath(argv[0]); err != nil {
+				t.Skipf("%s is not installed", argv[0])
+			}
+			for _, value := range values {
+				argv := runners[sh](Script(sh, map[string]string{"V": value}))
+				out, err := exec.Command(argv[0], argv[1:]...).Output()
+				if err != nil {
+					t.Fatalf("%s: %v", argv[0], err)
+				}
+				if got := strings.TrimSuffix(string(out), "\x00"); got != value {
+					t.Errorf("%q came back as %q", value, got)
+				}
+			}
+		})
+	}
+}
+
+func TestExport(t *testing.T) {
+	envFile := filepath.Join(t.TempDir(), "env")
+	if err := os.WriteFile(envFile, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv(EnvFileVar, "")
+	if err := Export(map[string]string{"AWS_PROFILE": "dev"}); err == nil {
+		t.Error("expected an error without the wrapper")
+	}
+
+	t.Setenv(EnvFileVar, envFile)
+	t.Setenv(ShellVar, "fish")
+	for _, profile := range []string{"dev", "prod"} {
+		if err := Export(map[string]string{"AWS_PROFILE": profile}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := os.ReadFile(envFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "set -gx AWS_PROFILE 'dev'\nset -gx AWS_PROFILE 'prod'\n"
+	if string(data) != want {
+		t.Errorf("env file = %q, want %q", data, want)
+	}
+}
@@ -0,0 +1,122 @@
+// Package ssoaccounts lists the accounts and roles an IAM Identity Center
+// access token can reach, using the SSO portal API.
+package ssoaccounts
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sso"
+	"github.com/aws/aws-sdk-go-v2/service/sso/types"
+)
+
+// EndpointEnv overrides the SSO portal endpoint, e.g. to test against a
+// local stub. It is the variable the AWS SDKs use for the same purpose.
+const EndpointEnv = "AWS_ENDPOINT_URL_SSO"
+
+// Account is an account the token can reach.
+type Account struct {
+	ID    string
+	Name  string
+	Email string
+}
+
+// Assignment is one role in one account.
+type Assignment struct {
+	AccountID   string
+	AccountName string
+	Email       string
+	RoleName    string
+}
+
+// Client calls the SSO portal API with a cached access token.
+type Client struct {
+	api   *sso.Client
+	token string
+}
+
+// New creates a client for the portal in region. An empty endpoint uses the
+// regional AWS endpoint.
+func New(region, endpoint, token string) *Client {
+	opts := sso.Options{Region: region}
+	if endpoint != "" {
+		opts.BaseEndpoint = aws.String(endpoint)
+	}
+	return &Client{api: sso.New(opts), token: token}
+}
+
+// IsUnauthorized reports whether err means the token was rejected, usually
+// because it expired or the session was signed out.
+func IsUnauthorized(err error) bool {
+	var unauthorized *types.UnauthorizedException
+	return errors.As(err, &unauthorized)
+}
+
+// Accounts lists every account the token can reach, sorted by name.
+func (c *Client) Accounts(ctx context.Context) ([]Account, error) {
+	var accounts []Account
+	pages := sso.NewListAccountsPaginator(c.api, &sso.ListAccountsInput{AccessToken: aws.String(c.token)})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, info := range page.AccountList {
+			accounts = append(accounts, Account{
+				ID:    aws.ToString(info.AccountId),
+				Name:  aws.ToString(info.AccountName),
+				Email: aws.ToString(info.EmailAddress),
+			})
+		}
+	}
+	sort.Slice(accounts, func(i, j int) bool {
+		return accounts[i].Name < accounts[j].Name
+	})
+	return accounts, nil
+}
+
+// Roles lists the roles the token can use in one account, sorted.
+func (c *Client) Roles(ctx context.Context, accountID string) ([]string, error) {
+	var roles []string
+	pages := sso.NewListAccountRolesPaginator(c.api, &sso.ListAccountRolesInput{
+		AccessToken: aws.String(c.token),
+		AccountId:   aws.String(accountID),
+	})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, info := range page.RoleList {
+			roles = append(roles, aws.ToString(info.RoleName))
+		}
+	}
+	sort.Strings(roles)
+	return roles, nil
+}
+
+// Assignments lists every role in every account the token can reach.
+func (c *Client) Assignments(ctx context.Context) ([]Assignment, error) {
+	accounts, err := c.Accounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var assignments []Assignment
+	for _, acct := range accounts {
+		roles, err := c.Roles(ctx, acct.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, role := range roles {
+			assignments = append(assignments, Assignment{
+				AccountID:   acct.ID,
+				AccountName: acct.Name,
+				Email:       acct.Email,
+				RoleName:    role,
+			})
+		}
+	}
+	return assignments, nil
+}
@@ -0,0 +1,104 @@
+// Package ssocache reads the IAM Identity Center tokens the AWS CLI caches
+// under ~/.aws/sso/cache after `aws sso login`.
+package ssocache
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aphexlog/gsd/internal/profiles"
+)
+
+// Token is a cached SSO access token.
+type Token struct {
+	StartURL     string `json:"startUrl"`
+	Region       string `json:"region"`
+	AccessToken  string `json:"accessToken"`
+	ExpiresAt    string `json:"expiresAt"`
+	RefreshToken string `json:"refreshToken,omitempty"`
+}
+
+// Dir returns the token cache directory.
+func Dir() string {
+	return filepath.Join(profiles.AWSDir(), "sso", "cache")
+}
+
+// Path returns the cache file for a key. The key is the sso-session name,
+// or the start URL for legacy profiles without a session.
+func Path(key string) string {
+	sum := sha1.Sum([]byte(key))
+	return filepath.Join(Dir(), hex.EncodeToString(sum[:])+".json")
+}
+
+// Load reads the cached token for key.
+func Load(key string) (*Token, error) {
+	data, err := os.ReadFile(Path(key))
+	if err != nil {
+		return nil, err
+	}
+	var token Token
+	if err := json.Unmarshal(data, &token); err != nil {
+		return nil, fmt.Errorf("read %s: %w", Path(key), err)
+	}
+	return &token, nil
+}
+
+// LoadForProfile returns the cached token used by an SSO profile.
+func LoadForProfile(p *profiles.Profile) (*Token, error) {
+	if p.SSOSession != "" {
+		return Load(p.SSOSession)
+	}
+	return Load(p.SSOStartURL)
+}
+
+// Expiry parses ExpiresAt. Older CLI versions write "UTC" instead of "Z".
+func (t *Token) Expiry() (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05UTC"} {
+		if ts, err := time.Parse(layout, t.ExpiresAt); err == nil {
+			return ts, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognised expiry %q", t.ExpiresAt)
+}
+
+// Valid reports whether the token has not expired yet.
+func (t *Token) Valid() bool {
+	expiry, err := t.Expiry()
+	return err == nil && t.AccessToken != "" && time.Now().Before(expiry)
+}
+
+// Status describes a cached token for display, e.g. "valid (2h15m left)".
+func Status(token *Token, err error) string {
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "not logged in"
+		}
+		return "unreadable"
+	}
+	expiry, err := token.Expiry()
+	if err != nil {
+		return "unreadable"
+	}
+	if !token.Valid() {
+		return "expired"
+	}
+	return fmt.Sprintf("valid (%s left)", FormatRemaining(time.Until(expiry)))
+}
+
+// FormatRemaining renders a duration compactly, e.g. "2d", "3h05m" or "12m".
+func FormatRemaining(d time.Duration) string {
+	d = d.Round(time.Minute)
+	switch {
+	case d >= 48*time.Hour:
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh%02dm", d/time.Hour, (d%time.Hour)/time.Minute)
+	default:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	}
+}
@@ -0,0 +1,175 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aphexlog/gsd/internal/fsutil"
+)
+
+// History limits. The oldest snapshots are pruned once either is exceeded.
+const (
+	MaxSnapshots     = 50
+	MaxHistoryBytes  = 10 << 20
+	snapshotIDFormat = "20060102-150405.000"
+)
+
+// Snapshot is the content of the AWS files from just before a gsd command
+// changed them.
+type Snapshot struct {
+	ID              string    `json:"id"`
+	Time            time.Time `json:"time"`
+	Command         string    `json:"command"`
+	Summary         []string  `json:"summary"`
+	Restores        string    `json:"restores,omitempty"`
+	ConfigPath      string    `json:"config_path"`
+	CredentialsPath string    `json:"credentials_path"`
+	Config          string    `json:"config"`
+	Credentials     string    `json:"credentials"`
+}
+
+func historyDir() string {
+	return filepath.Join(Dir(), "history")
+}
+
+// SaveSnapshot stores a snapshot and prunes old ones.
+func SaveSnapshot(snap *Snapshot) error {
+	dir := historyDir()
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	if snap.Time.IsZero() {
+		snap.Time = time.Now()
+	}
+	snap.ID = snap.Time.UTC().Format(snapshotIDFormat)
+	// Keep IDs unique if two snapshots land in the same millisecond.
+	for i := 1; ; i++ {
+		if _, err := os.Stat(snapshotPath(snap.ID)); errors.Is(err, fs.ErrNotExist) {
+			break
+		}
+		snap.ID = fmt.Sprintf("%s-%d", snap.Time.UTC().Format(snapshotIDFormat), i)
+	}
+
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := fsutil.WriteFileAtomic(snapshotPath(snap.ID), data, 0600); err != nil {
+		return err
+	}
+	return pruneHistory()
+}
+
+// Snapshots returns all snapshots, newest first.
+func Snapshots() ([]*Snapshot, error) {
+	entries, err := os.ReadDir(historyDir())
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var snaps []*Snapshot
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		snap, err := LoadSnapshot(strings.TrimSuffix(entry.Name(), ".json"))
+		if err != nil {
+			continue
+		}
+		snaps = append(snaps, snap)
+	}
+	sort.Slice(snaps, func(i, j int) bool {
+		return snaps[i].Time.After(snaps[j].Time)
+	})
+	return snaps, nil
+}
+
+// LoadSnapshot reads a single snapshot by ID.
+func LoadSnapshot(id string) (*Snapshot, error) {
+	if strings.ContainsAny(id, `/\`) {
+		return nil, fmt.Errorf("invalid snapshot id '%s'", id)
+	}
+	data, err := os.ReadFile(snapshotPath(id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("snapshot '%s' not found", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var snap Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("read snapshot '%s': %w", id, err)
+	}
+	return &snap, nil
+}
+
+// UndoTarget returns the snapshot `config undo` should restore: the newest
+// one that was not created by a restore and has not already been undone.
+// Repeated undos therefore walk further back through history.
+func UndoTarget() (*Snapshot, error) {
+	snaps, err := Snapshots()
+	if err != nil {
+		return nil, err
+	}
+	undone := make(map[string]bool)
+	for _, snap := range snaps {
+		if snap.Restores != "" {
+			undone[snap.Restores] = true
+		}
+	}
+	for _, snap := range snaps {
+		if snap.Restores == "" && !undone[snap.ID] {
+			return snap, nil
+		}
+	}
+	return nil, errors.New("nothing to undo")
+}
+
+func snapshotPath(id string) string {
+	return filepath.Join(historyDir(), id+".json")
+}
+
+func pruneHistory() error {
+	entries, err := os.ReadDir(historyDir())
+	if err != nil {
+		return err
+	}
+
+	type file struct {
+		name string
+		size int64
+	}
+	var files []file
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, file{entry.Name(), info.Size()})
+	}
+	// IDs are timestamps, so name order is age order.
+	sort.Slice(files, func(i, j int) bool { return files[i].name > files[j].name })
+
+	var total int64
+	for i, f := range files {
+		total += f.size
+		if i >= MaxSnapshots || (i > 0 && total > MaxHistoryBytes) {
+			os.Remove(filepath.Join(historyDir(), f.name))
+		}
+	}
+	return nil
+}
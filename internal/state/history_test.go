@@ -0,0 +1,51 @@
+package state
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUndoTarget(t *testing.T) {
+	// Each case lists snapshots oldest first, as "id" or "id>restored-id".
+	cases := []struct {
+		name  string
+		snaps [][2]string
+		want  string
+	}{
+		{"empty", nil, ""},
+		{"newest change", [][2]string{{"a", ""}, {"b", ""}}, "b"},
+		{"after one undo", [][2]string{{"a", ""}, {"b", ""}, {"u1", "b"}}, "a"},
+		{"after two undos", [][2]string{{"a", ""}, {"b", ""}, {"u1", "b"}, {"u2", "a"}}, ""},
+		{"change after undo", [][2]string{{"a", ""}, {"b", ""}, {"u1", "b"}, {"c", ""}}, "c"},
+		{"restore of an old snapshot", [][2]string{{"a", ""}, {"b", ""}, {"r", "a"}}, "b"},
+	}
+
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("GSD_STATE_DIR", t.TempDir())
+			ids := make(map[string]string)
+			for i, s := range tc.snaps {
+				snap := &Snapshot{Time: base.Add(time.Duration(i) * time.Minute), Command: s[0], Restores: ids[s[1]]}
+				if err := SaveSnapshot(snap); err != nil {
+					t.Fatal(err)
+				}
+				ids[s[0]] = snap.ID
+			}
+
+			snap, err := UndoTarget()
+			if tc.want == "" {
+				if err == nil {
+					t.Errorf("UndoTarget() = %s, want nothing to undo", snap.Command)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if snap.Command != tc.want {
+				t.Errorf("UndoTarget() = %s, want %s", snap.Command, tc.want)
+			}
+		})
+	}
+}
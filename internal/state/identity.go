@@ -0,0 +1,137 @@
+package state
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aphexlog/gsd/internal/fsutil"
+)
+
+// Identity is what STS last said a profile's credentials belong to. It is
+// cached so `gsd prompt` can show it without calling AWS.
+type Identity struct {
+	Account string    `json:"account"`
+	ARN     string    `json:"arn"`
+	Time    time.Time `json:"time"`
+}
+
+// identityCache is the content of identities.json. Accounts maps account
+// IDs to a display name: the IAM account alias, or the account name from
+// the SSO portal.
+type identityCache struct {
+	Profiles map[string]Identity `json:"profiles"`
+	Accounts map[string]string   `json:"accounts"`
+}
+
+func identityPath() string {
+	return filepath.Join(Dir(), "identities.json")
+}
+
+func loadIdentities() *identityCache {
+	cache := &identityCache{}
+	if data, err := os.ReadFile(identityPath()); err == nil {
+		_ = json.Unmarshal(data, cache)
+	}
+	if cache.Profiles == nil {
+		cache.Profiles = make(map[string]Identity)
+	}
+	if cache.Accounts == nil {
+		cache.Accounts = make(map[string]string)
+	}
+	return cache
+}
+
+func saveIdentities(cache *identityCache) error {
+	if err := os.MkdirAll(Dir(), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fsutil.WriteFileAtomic(identityPath(), data, 0600)
+}
+
+// updateIdentities applies fn to the cache under its lock, saving the
+// result if fn reports a change.
+func updateIdentities(fn func(*identityCache) bool) error {
+	return locked(identityPath(), func() error {
+		cache := loadIdentities()
+		if !fn(cache) {
+			return nil
+		}
+		return saveIdentities(cache)
+	})
+}
+
+// CachedIdentity returns the identity last seen for a profile.
+func CachedIdentity(profile string) (id Identity, ok bool) {
+	id, ok = loadIdentities().Profiles[profile]
+	return id, ok
+}
+
+// AccountAlias returns the display name cached for an account, or "".
+func AccountAlias(accountID string) string {
+	return loadIdentities().Accounts[accountID]
+}
+
+// CacheIdentity records a profile's identity.
+func CacheIdentity(profile string, id Identity) error {
+	if id.Time.IsZero() {
+		id.Time = time.Now()
+	}
+	return updateIdentities(func(cache *identityCache) bool {
+		cache.Profiles[profile] = id
+		return true
+	})
+}
+
+// CacheAccountAlias records the IAM alias of an account, replacing any name
+// learned elsewhere.
+func CacheAccountAlias(accountID, alias string) error {
+	return updateIdentities(func(cache *identityCache) bool {
+		cache.Accounts[accountID] = alias
+		return true
+	})
+}
+
+// RenameIdentity moves the identity cached for a renamed profile.
+func RenameIdentity(from, to string) error {
+	return updateIdentities(func(cache *identityCache) bool {
+		id, ok := cache.Profiles[from]
+		if !ok {
+			return false
+		}
+		delete(cache.Profiles, from)
+		cache.Profiles[to] = id
+		return true
+	})
+}
+
+// ForgetIdentity drops the identity cached for a removed profile.
+func ForgetIdentity(profile string) error {
+	return updateIdentities(func(cache *identityCache) bool {
+		if _, ok := cache.Profiles[profile]; !ok {
+			return false
+		}
+		delete(cache.Profiles, profile)
+		return true
+	})
+}
+
+// LearnAccountNames caches account names, such as those listed by the SSO
+// portal, for accounts that have no alias cached yet.
+func LearnAccountNames(names map[string]string) error {
+	return updateIdentities(func(cache *identityCache) bool {
+		changed := false
+		for id, name := range names {
+			if _, ok := cache.Accounts[id]; !ok && name != "" {
+				cache.Accounts[id] = name
+				changed = true
+			}
+		}
+		return changed
+	})
+}
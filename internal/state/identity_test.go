@@ -0,0 +1,37 @@
+package state
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestConcurrentUpdates(t *testing.T) {
+	t.Setenv("GSD_STATE_DIR", t.TempDir())
+
+	const n = 20
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			profile := fmt.Sprintf("p%d", i)
+			if err := CacheIdentity(profile, Identity{Account: "111111111111"}); err != nil {
+				t.Error(err)
+			}
+			if err := RecordSwitch(profile, false); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for i := range n {
+		if _, ok := CachedIdentity(fmt.Sprintf("p%d", i)); !ok {
+			t.Errorf("identity of p%d was lost", i)
+		}
+	}
+	if switches, _ := Switches(); len(switches) != n {
+		t.Errorf("recorded %d switches, want %d", len(switches), n)
+	}
+}
@@ -0,0 +1,41 @@
+// Package state manages gsd's own files: mutation history and other data
+// that must survive between runs but does not belong in ~/.aws.
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/aphexlog/gsd/internal/fsutil"
+	"github.com/aphexlog/gsd/internal/profiles"
+)
+
+// Dir returns the gsd state directory. GSD_STATE_DIR overrides it; otherwise
+// it follows XDG_STATE_HOME on Unix and LOCALAPPDATA on Windows.
+func Dir() string {
+	if dir := os.Getenv("GSD_STATE_DIR"); dir != "" {
+		return dir
+	}
+	if runtime.GOOS == "windows" {
+		if dir := os.Getenv("LOCALAPPDATA"); dir != "" {
+			return filepath.Join(dir, "gsd")
+		}
+	}
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "gsd")
+	}
+	return filepath.Join(profiles.HomeDir(), ".local", "state", "gsd")
+}
+
+// locked runs fn, a read-modify-write of the state file at path, holding an
+// advisory lock on it so concurrent gsd commands don't drop each other's
+// changes.
+func locked(path string, fn func() error) error {
+	lock, err := fsutil.AcquireLock(path + ".lock")
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+	return fn()
+}
@@ -0,0 +1,169 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aphexlog/gsd/internal/fsutil"
+	"github.com/aphexlog/gsd/internal/profiles"
+)
+
+// MaxSwitches is how many profile switches are remembered.
+const MaxSwitches = 200
+
+// Switch is one `gsd switch`. Global switches rewrote [default]; the others
+// only set AWS_PROFILE in one shell.
+type Switch struct {
+	Profile string    `json:"profile"`
+	Time    time.Time `json:"time"`
+	Global  bool      `json:"global,omitempty"`
+}
+
+func switchesPath() string {
+	return filepath.Join(Dir(), "switches.json")
+}
+
+// legacyCurrentPath is the file older versions of gsd wrote the profile
+// chosen by a global switch to.
+func legacyCurrentPath() string {
+	return filepath.Join(profiles.AWSDir(), ".gsd-current")
+}
+
+// Switches returns the remembered switches, newest first.
+func Switches() ([]Switch, error) {
+	data, err := fsutil.ReadFileIfExists(switchesPath())
+	if err != nil || data == nil {
+		return nil, err
+	}
+	var switches []Switch
+	if err := json.Unmarshal(data, &switches); err != nil {
+		return nil, err
+	}
+	return switches, nil
+}
+
+func saveSwitches(switches []Switch) error {
+	if len(switches) > MaxSwitches {
+		switches = switches[:MaxSwitches]
+	}
+	if err := os.MkdirAll(Dir(), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(switches, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fsutil.WriteFileAtomic(switchesPath(), data, 0600)
+}
+
+// RecordSwitch remembers a switch to profile. A global switch also retires
+// the legacy .gsd-current file, since the history now says the same.
+func RecordSwitch(profile string, global bool) error {
+	err := locked(switchesPath(), func() error {
+		switches, err := Switches()
+		if err != nil {
+			// A damaged history is not worth failing a switch over.
+			switches = nil
+		}
+		switches = append([]Switch{{Profile: profile, Time: time.Now(), Global: global}}, switches...)
+		return saveSwitches(switches)
+	})
+	if err != nil {
+		return err
+	}
+	if global {
+		if err := os.Remove(legacyCurrentPath()); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// GlobalProfile returns the profile last chosen with a global switch, or ""
+// if there was none.
+func GlobalProfile() string {
+	switches, _ := Switches()
+	for _, s := range switches {
+		if s.Global {
+			return s.Profile
+		}
+	}
+	if data, err := os.ReadFile(legacyCurrentPath()); err == nil {
+		return strings.TrimSpace(string(data))
+	}
+	return ""
+}
+
+// PreviousProfile returns the most recent profile switched to globally
+// other than current, which is what `gsd switch --global -` goes back to.
+// Switches in one shell are tracked by that shell instead.
+func PreviousProfile(current string) (string, error) {
+	switches, err := Switches()
+	if err != nil {
+		return "", err
+	}
+	for _, s := range switches {
+		if s.Global && s.Profile != current {
+			return s.Profile, nil
+		}
+	}
+	return "", errors.New("no previous profile to switch back to")
+}
+
+// FrequentProfiles returns up to limit profiles ranked by how often and how
+// recently they were switched to. Each switch counts for less the older it
+// is, halving every week.
+func FrequentProfiles(limit int) []string {
+	switches, _ := Switches()
+	now := time.Now()
+	scores := make(map[string]float64)
+	var names []string
+	for _, s := range switches {
+		if _, ok := scores[s.Profile]; !ok {
+			names = append(names, s.Profile)
+		}
+		age := now.Sub(s.Time).Hours() / (24 * 7)
+		scores[s.Profile] += math.Pow(0.5, math.Max(age, 0))
+	}
+	// names is in order of last use, which breaks ties.
+	sort.SliceStable(names, func(i, j int) bool {
+		return scores[names[i]] > scores[names[j]]
+	})
+	if len(names) > limit {
+		names = names[:limit]
+	}
+	return names
+}
+
+// RenameSwitches points remembered switches to a renamed profile.
+func RenameSwitches(from, to string) error {
+	if data, err := os.ReadFile(legacyCurrentPath()); err == nil && strings.TrimSpace(string(data)) == from {
+		if err := fsutil.WriteFileAtomic(legacyCurrentPath(), []byte(to), 0600); err != nil {
+			return err
+		}
+	}
+	return locked(switchesPath(), func() error {
+		switches, err := Switches()
+		if err != nil {
+			return err
+		}
+		renamed := false
+		for i := range switches {
+			if switches[i].Profile == from {
+				switches[i].Profile = to
+				renamed = true
+			}
+		}
+		if !renamed {
+			return nil
+		}
+		return saveSwitches(switches)
+	})
+}
@@ -0,0 +1,45 @@
+package state
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestFrequentProfiles(t *testing.T) {
+	day := 24 * time.Hour
+	// Each case lists switches newest first, as profile and age.
+	type sw struct {
+		profile string
+		age     time.Duration
+	}
+	cases := []struct {
+		name     string
+		switches []sw
+		limit    int
+		want     []string
+	}{
+		{"empty", nil, 5, nil},
+		{"count wins at the same age", []sw{{"a", 0}, {"b", 0}, {"b", 0}}, 5, []string{"b", "a"}},
+		{"old switches fade", []sw{{"a", 0}, {"b", 21 * day}, {"b", 21 * day}, {"b", 21 * day}}, 5, []string{"a", "b"}},
+		{"recent use breaks ties", []sw{{"b", 0}, {"a", 0}}, 5, []string{"b", "a"}},
+		{"a week halves", []sw{{"a", 0}, {"b", 7 * day}, {"b", 7 * day}, {"b", 7 * day}}, 5, []string{"b", "a"}},
+		{"limit", []sw{{"a", 0}, {"b", day}, {"c", 2 * day}}, 2, []string{"a", "b"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("GSD_STATE_DIR", t.TempDir())
+			now := time.Now()
+			var switches []Switch
+			for _, s := range tc.switches {
+				switches = append(switches, Switch{Profile: s.profile, Time: now.Add(-s.age)})
+			}
+			if err := saveSwitches(switches); err != nil {
+				t.Fatal(err)
+			}
+			if got := FrequentProfiles(tc.limit); !slices.Equal(got, tc.want) {
+				t.Errorf("FrequentProfiles() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package state
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/aphexlog/gsd/internal/fsutil"
+)
+
+// Pin files are only acted on once the user has allowed them. Trust is
+// recorded per path together with a hash of the content, so editing a file
+// (or pulling someone else's edit) requires allowing it again.
+
+func trustPath() string {
+	return filepath.Join(Dir(), "trusted.json")
+}
+
+func loadTrust() (map[string]string, error) {
+	trusted := make(map[string]string)
+	data, err := fsutil.ReadFileIfExists(trustPath())
+	if err != nil || data == nil {
+		return trusted, err
+	}
+	if err := json.Unmarshal(data, &trusted); err != nil {
+		return nil, err
+	}
+	return trusted, nil
+}
+
+func saveTrust(trusted map[string]string) error {
+	if err := os.MkdirAll(Dir(), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(trusted, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fsutil.WriteFileAtomic(trustPath(), data, 0600)
+}
+
+// ContentHash is the hash a file's content is trusted by.
+func ContentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Trusted reports whether the file at path was allowed with this content.
+func Trusted(path string, data []byte) bool {
+	trusted, err := loadTrust()
+	return err == nil && trusted[path] == ContentHash(data)
+}
+
+// Trust allows the file at path with its current content.
+func Trust(path string, data []byte) error {
+	trusted, err := loadTrust()
+	if err != nil {
+		return err
+	}
+	trusted[path] = ContentHash(data)
+	return saveTrust(trusted)
+}
+
+// TrustedPaths returns the paths of every allowed file, sorted.
+func TrustedPaths() ([]string, error) {
+	trusted, err := loadTrust()
+	if err != nil {
+		return nil, err
+	}
+	paths := make([]string, 0, len(trusted))
+	for path := range trusted {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// Untrust revokes a file, reporting whether it was allowed before.
+func Untrust(path string) (bool, error) {
+	trusted, err := loadTrust()
+	if err != nil {
+		return false, err
+	}
+	if _, ok := trusted[path]; !ok {
+		return false, nil
+	}
+	delete(trusted, path)
+	return true, saveTrust(trusted)
+}